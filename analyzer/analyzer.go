@@ -1,14 +1,30 @@
 // Package analyzer provides an interface for analyzing source code for compatibility issues.
 package analyzer
 
+import "fmt"
+
 // Analyzer represents the interface for the analyzer.
 type Analyzer interface {
-	// Analyze analyzes the provided source code and returns any issues found.
-	// TODO: better to update the code to take a reader interface instead of path
-	Analyze(path string, withTrace bool) ([]*Issue, error)
+	// Analyze analyzes the provided program and returns any issues found.
+	Analyze(program *Program, withTrace bool) ([]*Issue, error)
 
 	// TraceStack generates callstack for a function to debug
-	TraceStack(path string, function string) (*CallStack, error)
+	TraceStack(program *Program, function string) (*CallStack, error)
+}
+
+// Program is the input handed to an Analyzer.
+type Program struct {
+	// Path is the Go source path of the program, or of its binary, used by source level analyzers.
+	Path string
+	// Disassembly is the in-memory disassembler output, used by assembly level analyzers.
+	Disassembly []byte
+	// DisassemblyPath is the file the text disassembly is stored in, if any. The call stacks of
+	// assembly level analyzers then point at its lines alongside the address of every frame.
+	DisassemblyPath string
+	// Binary is the compiled ELF program, used to map instructions back to Go source lines
+	// and to resolve the targets of indirect calls from its data sections.
+	// It is optional; without it call stacks point into the disassembly.
+	Binary []byte
 }
 
 // IssueSeverity represents the severity level of an issue.
@@ -26,6 +42,8 @@ type Issue struct {
 	Severity  IssueSeverity `json:"severity"`
 	Impact    string        `json:"impact,omitempty"`
 	Reference string        `json:"reference,omitempty"`
+	Binary    string        `json:"binary,omitempty"`    // The binary the issue was found in.
+	CallGraph string        `json:"callGraph,omitempty"` // The call graph algorithm of the SSA analysis that found the issue.
 }
 
 // CallStack represents a location in the code where the issue originates.
@@ -35,6 +53,25 @@ type CallStack struct {
 	Function  string     `json:"function"`            // The function where the issue was found.
 	AbsPath   string     `json:"absPath"`             // The absolute file path.
 	CallStack *CallStack `json:"callStack,omitempty"` // The trace of calls leading to this source.
+	Inlined   bool       `json:"inlined,omitempty"`   // The function was inlined into its caller.
+	// The location in the disassembly, set for call stacks of assembly level analyzers.
+	Disassembly *DisassemblyLocation `json:"disassembly,omitempty"`
+}
+
+// DisassemblyLocation represents the location of an instruction in the disassembly.
+// File and Line are only set when the text disassembly is stored in a file.
+type DisassemblyLocation struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Address string `json:"address"` // The memory address of the instruction.
+}
+
+// String returns the address of the instruction, followed by its line in the stored disassembly if any.
+func (l *DisassemblyLocation) String() string {
+	if l.File == "" {
+		return l.Address
+	}
+	return fmt.Sprintf("%s %s:%d", l.Address, l.File, l.Line)
 }
 
 // Copy creates a deep copy of the CallStack.
@@ -48,12 +85,20 @@ func (src *CallStack) Copy() *CallStack {
 		copiedCallStack = src.CallStack.Copy()
 	}
 
+	var copiedDisassembly *DisassemblyLocation
+	if src.Disassembly != nil {
+		location := *src.Disassembly
+		copiedDisassembly = &location
+	}
+
 	return &CallStack{
-		File:      src.File,
-		Line:      src.Line,
-		Function:  src.Function,
-		AbsPath:   src.AbsPath,
-		CallStack: copiedCallStack,
+		File:        src.File,
+		Line:        src.Line,
+		Function:    src.Function,
+		AbsPath:     src.AbsPath,
+		CallStack:   copiedCallStack,
+		Inlined:     src.Inlined,
+		Disassembly: copiedDisassembly,
 	}
 }
 
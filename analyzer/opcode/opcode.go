@@ -2,14 +2,16 @@
 package opcode
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
-	"path/filepath"
 	"slices"
 	"strings"
 
 	"github.com/ChainSafe/vm-compat/analyzer"
 	"github.com/ChainSafe/vm-compat/asmparser"
 	"github.com/ChainSafe/vm-compat/asmparser/mips"
+	"github.com/ChainSafe/vm-compat/asmparser/riscv"
 	"github.com/ChainSafe/vm-compat/common"
 	"github.com/ChainSafe/vm-compat/profile"
 )
@@ -22,24 +24,27 @@ func NewAnalyser(profile *profile.VMProfile) analyzer.Analyzer {
 	return &opcode{profile: profile}
 }
 
-func (op *opcode) Analyze(path string, withTrace bool) ([]*analyzer.Issue, error) {
-	callGraph, err := op.buildCallGraph(path)
+func (op *opcode) Analyze(program *analyzer.Program, withTrace bool) ([]*analyzer.Issue, error) {
+	callGraph, err := op.buildCallGraph(program)
 	if err != nil {
 		return nil, err
 	}
 
-	absPath, err := filepath.Abs(path)
+	disassemblyPath, err := common.DisassemblyPath(program)
 	if err != nil {
 		return nil, err
 	}
+	lines := common.ProgramLines(program)
 	issues := make([]*analyzer.Issue, 0)
 	for _, segment := range callGraph.Segments() {
 		for _, instruction := range segment.Instructions() {
-			if !op.isAllowedOpcode(instruction.OpcodeHex(), instruction.Funct()) {
+			if !op.isAllowedOpcode(instruction) {
 				source, err := common.TraceAsmCaller(
-					absPath,
+					disassemblyPath,
 					callGraph,
 					segment.Label(),
+					instruction,
+					lines,
 					common.ProgramEntrypoint(op.profile.GOARCH),
 				)
 				if err != nil { // non-reachable portion ignored
@@ -48,8 +53,9 @@ func (op *opcode) Analyze(path string, withTrace bool) ([]*analyzer.Issue, error
 				issue := &analyzer.Issue{
 					Severity:  analyzer.IssueSeverityCritical,
 					CallStack: source,
-					Message: fmt.Sprintf("Potential Incompatible Opcode Detected: Opcode: %s, Funct: %s",
-						instruction.OpcodeHex(), instruction.Funct()),
+					Message: fmt.Sprintf("Potential Incompatible Opcode Detected: %s (Opcode: %s, Funct: %s%s)",
+						instruction.BaseMnemonic(), instruction.OpcodeHex(), instruction.Funct(),
+						formatFields(instruction.Fields())),
 				}
 				if common.ShouldIgnoreSource(source, op.profile.IgnoredFunctions) {
 					issue.Severity = analyzer.IssueSeverityWarning
@@ -64,16 +70,22 @@ func (op *opcode) Analyze(path string, withTrace bool) ([]*analyzer.Issue, error
 	return issues, nil
 }
 
-func (op *opcode) buildCallGraph(path string) (asmparser.CallGraph, error) {
+func (op *opcode) buildCallGraph(program *analyzer.Program) (asmparser.CallGraph, error) {
 	var (
 		err       error
 		callGraph asmparser.CallGraph
 	)
+	// The binary provides the targets of indirect calls when the disassembly is text.
+	withBinary := asmparser.WithBinary(program.Binary)
 
 	// Select the correct parser based on architecture.
 	switch op.profile.GOARCH {
 	case "mips", "mips64":
-		callGraph, err = mips.NewParser().Parse(path)
+		callGraph, err = mips.NewParser(binary.BigEndian, withBinary).Parse(bytes.NewReader(program.Disassembly))
+	case "mipsle", "mips64le":
+		callGraph, err = mips.NewParser(binary.LittleEndian, withBinary).Parse(bytes.NewReader(program.Disassembly))
+	case "riscv64":
+		callGraph, err = riscv.NewParser(withBinary).Parse(bytes.NewReader(program.Disassembly))
 	default:
 		return nil, fmt.Errorf("unsupported GOARCH: %s", op.profile.GOARCH)
 	}
@@ -84,27 +96,62 @@ func (op *opcode) buildCallGraph(path string) (asmparser.CallGraph, error) {
 }
 
 // TraceStack generates callstack for a function to debug
-func (op *opcode) TraceStack(path string, function string) (*analyzer.CallStack, error) {
-	graph, err := op.buildCallGraph(path)
+func (op *opcode) TraceStack(program *analyzer.Program, function string) (*analyzer.CallStack, error) {
+	graph, err := op.buildCallGraph(program)
 	if err != nil {
 		return nil, err
 	}
-	absPath, err := filepath.Abs(path)
+	disassemblyPath, err := common.DisassemblyPath(program)
 	if err != nil {
 		return nil, err
 	}
-	return common.TraceAsmCaller(absPath, graph, function, common.ProgramEntrypoint(op.profile.GOARCH))
+	return common.TraceAsmCaller(
+		disassemblyPath,
+		graph,
+		function,
+		nil,
+		common.ProgramLines(program),
+		common.ProgramEntrypoint(op.profile.GOARCH),
+	)
 }
-func (op *opcode) isAllowedOpcode(opcode, funct string) bool {
+func (op *opcode) isAllowedOpcode(instruction asmparser.Instruction) bool {
+	funct := instruction.Funct()
+	fields := instruction.Fields()
 	return slices.ContainsFunc(op.profile.AllowedOpcodes, func(instr profile.OpcodeInstruction) bool {
-		if !strings.EqualFold(instr.Opcode, opcode) {
+		if instr.Mnemonic != "" {
+			return strings.EqualFold(instr.Mnemonic, instruction.BaseMnemonic())
+		}
+		if !strings.EqualFold(instr.Opcode, instruction.OpcodeHex()) {
 			return false
 		}
+		for name, allowed := range instr.Fields() {
+			if len(allowed) > 0 && !containsFold(allowed, fields[name]) {
+				return false
+			}
+		}
 		if len(instr.Funct) == 0 {
 			return funct == ""
 		}
-		return slices.ContainsFunc(instr.Funct, func(s string) bool {
-			return strings.EqualFold(s, funct)
-		})
+		return containsFold(instr.Funct, funct)
 	})
 }
+
+func containsFold(values []string, value string) bool {
+	return slices.ContainsFunc(values, func(s string) bool {
+		return strings.EqualFold(s, value)
+	})
+}
+
+// formatFields renders the sub-fields of an instruction for the issue message, in a stable order.
+func formatFields(fields map[string]string) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf(", %s: %s", strings.ToUpper(name[:1])+name[1:], fields[name]))
+	}
+	return sb.String()
+}
@@ -2,14 +2,17 @@
 package syscall
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
-	"path/filepath"
 	"slices"
 
 	"github.com/ChainSafe/vm-compat/analyzer"
 	"github.com/ChainSafe/vm-compat/asmparser"
 	"github.com/ChainSafe/vm-compat/asmparser/mips"
+	"github.com/ChainSafe/vm-compat/asmparser/riscv"
 	"github.com/ChainSafe/vm-compat/common"
+	"github.com/ChainSafe/vm-compat/common/lineinfo"
 	"github.com/ChainSafe/vm-compat/profile"
 )
 
@@ -17,6 +20,12 @@ const (
 	analyzerWorkingPrincipalURL = "https://github.com/ChainSafe/vm-compat?tab=readme-ov-file#how-it-works"
 	potentialImpactMsg          = `This syscall is present in the program, but its execution depends on the actual runtime behavior. 
              If the execution path does not reach this syscall, it may not affect execution.`
+	unresolvedCallImpactMsg = `The target of this indirect call could not be resolved, so the functions it reaches
+             are missing from the call graph. Syscalls only reachable through it are not reported.`
+	unresolvedSyscallImpactMsg = `The number of this syscall could not be determined along this path, so the syscall
+             it performs there is not checked against the profile.`
+	unbuiltPackageImpactMsg = `The SSA form of this package could not be built, so its functions are missing from
+             the call graph. Syscalls only reachable through them are not reported.`
 )
 
 // asmSyscallAnalyser analyzes system calls in assembly files.
@@ -30,18 +39,37 @@ func NewAssemblySyscallAnalyser(profile *profile.VMProfile) analyzer.Analyzer {
 }
 
 // Analyze scans an assembly file for syscalls and detects compatibility issues.
-//
-//nolint:cyclop
-func (a *asmSyscallAnalyser) Analyze(path string, withTrace bool) ([]*analyzer.Issue, error) {
-	callGraph, err := a.buildCallGraph(path)
+func (a *asmSyscallAnalyser) Analyze(program *analyzer.Program, withTrace bool) ([]*analyzer.Issue, error) {
+	findings, issues, err := a.findSyscalls(program, withTrace, func(num int) bool {
+		return !slices.Contains(a.profile.AllowedSycalls, num)
+	})
 	if err != nil {
 		return nil, err
 	}
-	absPath, err := filepath.Abs(path)
+	for _, finding := range findings {
+		issues = append(issues, syscallIssue(a.profile, finding, withTrace))
+	}
+	return issues, nil
+}
+
+// findSyscalls returns the reachable syscalls of the program whose number is kept by keep, along
+// with the issues about the syscall numbers and indirect calls that could not be resolved.
+func (a *asmSyscallAnalyser) findSyscalls(
+	program *analyzer.Program,
+	withTrace bool,
+	keep func(num int) bool,
+) ([]*syscallFinding, []*analyzer.Issue, error) {
+	callGraph, err := a.buildCallGraph(program)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	disassemblyPath, err := common.DisassemblyPath(program)
+	if err != nil {
+		return nil, nil, err
 	}
 
+	lines := common.ProgramLines(program)
+	findings := make([]*syscallFinding, 0)
 	issues := make([]*analyzer.Issue, 0)
 	// Iterate through segments and check for syscall.
 	for _, segment := range callGraph.Segments() {
@@ -49,60 +77,130 @@ func (a *asmSyscallAnalyser) Analyze(path string, withTrace bool) ([]*analyzer.I
 			if !instruction.IsSyscall() {
 				continue
 			}
-			syscalls, err := callGraph.RetrieveSyscallNum(segment, instruction)
+			syscalls, unresolved, err := callGraph.RetrieveSyscallNum(segment, instruction)
 			if err != nil {
-				return nil, fmt.Errorf("failed to retrieve syscall number: %w", err)
+				return nil, nil, fmt.Errorf("failed to retrieve syscall number: %w", err)
 			}
+			issues = append(issues, a.unresolvedSyscalls(callGraph, segment, instruction, unresolved, disassemblyPath, lines, withTrace)...)
 			for _, syscall := range syscalls {
-				// Categorize syscall
-				if slices.Contains(a.profile.AllowedSycalls, syscall.Number) {
+				if !keep(syscall.Number) {
 					continue
 				}
 				source, err := common.TraceAsmCaller(
-					absPath,
+					disassemblyPath,
 					callGraph,
 					syscall.Segment.Label(),
+					syscall.Instruction,
+					lines,
 					common.ProgramEntrypoint(a.profile.GOARCH),
 				)
 				if err != nil { // non-reachable portion ignored
 					continue
 				}
-
-				severity := analyzer.IssueSeverityCritical
-				if common.ShouldIgnoreSource(source, a.profile.IgnoredFunctions) {
-					severity = analyzer.IssueSeverityWarning
-				}
-				message := fmt.Sprintf("Potential Incompatible Syscall Detected: %d", syscall.Number)
-				if slices.Contains(a.profile.NOOPSyscalls, syscall.Number) {
-					message = fmt.Sprintf("Potential NOOP Syscall Detected: %d", syscall.Number)
-					severity = analyzer.IssueSeverityWarning
-				}
-				if !withTrace {
-					source.CallStack = nil
-				}
-				issues = append(issues, &analyzer.Issue{
-					Severity:  severity,
-					Message:   message,
-					CallStack: source,
-					Impact:    potentialImpactMsg,
-					Reference: analyzerWorkingPrincipalURL,
-				})
+				// The first frame is the function setting the number, or the function inlined there
+				findings = append(findings, &syscallFinding{num: syscall.Number, function: source.Function, source: source})
 			}
 		}
 	}
-	return issues, nil
+	return findings, append(issues, a.unresolvedCalls(callGraph, disassemblyPath, lines, withTrace)...), nil
 }
 
-func (a *asmSyscallAnalyser) buildCallGraph(path string) (asmparser.CallGraph, error) {
+// unresolvedSyscalls reports the paths along which the number of a reachable syscall instruction
+// could not be determined.
+func (a *asmSyscallAnalyser) unresolvedSyscalls(
+	callGraph asmparser.CallGraph,
+	segment asmparser.Segment,
+	instruction asmparser.Instruction,
+	unresolved []*asmparser.UnresolvedSyscall,
+	disassemblyPath string,
+	lines *lineinfo.Table,
+	withTrace bool,
+) []*analyzer.Issue {
+	if len(unresolved) == 0 {
+		return nil
+	}
+	source, err := common.TraceAsmCaller(
+		disassemblyPath,
+		callGraph,
+		segment.Label(),
+		instruction,
+		lines,
+		common.ProgramEntrypoint(a.profile.GOARCH),
+	)
+	if err != nil { // non-reachable portion ignored
+		return nil
+	}
+	if !withTrace {
+		source.CallStack = nil
+	}
+	issues := make([]*analyzer.Issue, 0, len(unresolved))
+	for _, path := range unresolved {
+		issues = append(issues, &analyzer.Issue{
+			Severity: analyzer.IssueSeverityWarning,
+			Message: fmt.Sprintf("Unresolved Syscall Number at %s in %s: %s (%s at %s in %s)",
+				instruction.Address(), segment.Label(), path.Reason,
+				path.Instruction.Mnemonic(), path.Instruction.Address(), path.Segment.Label()),
+			CallStack: source.Copy(),
+			Impact:    unresolvedSyscallImpactMsg,
+			Reference: analyzerWorkingPrincipalURL,
+		})
+	}
+	return issues
+}
+
+// unresolvedCalls reports the reachable indirect calls whose targets could not be resolved.
+func (a *asmSyscallAnalyser) unresolvedCalls(
+	callGraph asmparser.CallGraph,
+	disassemblyPath string,
+	lines *lineinfo.Table,
+	withTrace bool,
+) []*analyzer.Issue {
+	issues := make([]*analyzer.Issue, 0)
+	for _, call := range callGraph.IndirectCalls() {
+		if call.Resolved() {
+			continue
+		}
+		source, err := common.TraceAsmCaller(
+			disassemblyPath,
+			callGraph,
+			call.Segment.Label(),
+			call.Instruction,
+			lines,
+			common.ProgramEntrypoint(a.profile.GOARCH),
+		)
+		if err != nil { // non-reachable portion ignored
+			continue
+		}
+		if !withTrace {
+			source.CallStack = nil
+		}
+		issues = append(issues, &analyzer.Issue{
+			Severity:  analyzer.IssueSeverityWarning,
+			Message:   fmt.Sprintf("Unresolved Indirect Call: %s (%s)", call.Instruction.Mnemonic(), call.Kind),
+			CallStack: source,
+			Impact:    unresolvedCallImpactMsg,
+			Reference: analyzerWorkingPrincipalURL,
+		})
+	}
+	return issues
+}
+
+func (a *asmSyscallAnalyser) buildCallGraph(program *analyzer.Program) (asmparser.CallGraph, error) {
 	var (
 		err       error
 		callGraph asmparser.CallGraph
 	)
+	// The binary provides the targets of indirect calls when the disassembly is text.
+	withBinary := asmparser.WithBinary(program.Binary)
 
 	// Select the correct parser based on architecture.
 	switch a.profile.GOARCH {
 	case "mips", "mips64":
-		callGraph, err = mips.NewParser().Parse(path)
+		callGraph, err = mips.NewParser(binary.BigEndian, withBinary).Parse(bytes.NewReader(program.Disassembly))
+	case "mipsle", "mips64le":
+		callGraph, err = mips.NewParser(binary.LittleEndian, withBinary).Parse(bytes.NewReader(program.Disassembly))
+	case "riscv64":
+		callGraph, err = riscv.NewParser(withBinary).Parse(bytes.NewReader(program.Disassembly))
 	default:
 		return nil, fmt.Errorf("unsupported GOARCH: %s", a.profile.GOARCH)
 	}
@@ -113,15 +211,22 @@ func (a *asmSyscallAnalyser) buildCallGraph(path string) (asmparser.CallGraph, e
 }
 
 // TraceStack generates callstack for a function to debug
-func (a *asmSyscallAnalyser) TraceStack(path string, function string) (*analyzer.CallStack, error) {
-	graph, err := a.buildCallGraph(path)
+func (a *asmSyscallAnalyser) TraceStack(program *analyzer.Program, function string) (*analyzer.CallStack, error) {
+	graph, err := a.buildCallGraph(program)
 	if err != nil {
 		return nil, err
 	}
 
-	absPath, err := filepath.Abs(path)
+	disassemblyPath, err := common.DisassemblyPath(program)
 	if err != nil {
 		return nil, err
 	}
-	return common.TraceAsmCaller(absPath, graph, function, common.ProgramEntrypoint(a.profile.GOARCH))
+	return common.TraceAsmCaller(
+		disassemblyPath,
+		graph,
+		function,
+		nil,
+		common.ProgramLines(program),
+		common.ProgramEntrypoint(a.profile.GOARCH),
+	)
 }
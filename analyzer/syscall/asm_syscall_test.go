@@ -0,0 +1,107 @@
+package syscall
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/ChainSafe/vm-compat/analyzer"
+	"github.com/ChainSafe/vm-compat/common"
+	"github.com/ChainSafe/vm-compat/disassembler"
+	"github.com/ChainSafe/vm-compat/disassembler/goobjdump"
+	"github.com/ChainSafe/vm-compat/disassembler/objdump"
+	"github.com/ChainSafe/vm-compat/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeDeterministic(t *testing.T) {
+	prof, err := profile.LoadProfile("../../profile/cannon/cannon-multithreaded-64.yaml")
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "sample")
+	require.NoError(t, common.BuildBinary("../../examples/sample.go", prof.GOOS, prof.GOARCH, path, nil))
+	binary, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	// Indirect calls add many parents, the trace and the severity must not depend on map order
+	program := &analyzer.Program{Path: path, Disassembly: binary, Binary: binary}
+	first, err := NewAssemblySyscallAnalyser(prof).Analyze(program, true)
+	require.NoError(t, err)
+	assert.NotEmpty(t, first)
+	for i := 0; i < 2; i++ {
+		issues, err := NewAssemblySyscallAnalyser(prof).Analyze(program, true)
+		require.NoError(t, err)
+		assert.Equal(t, first, issues)
+	}
+	// Without a stored text disassembly the frames only point at Go source lines and addresses
+	for _, issue := range first {
+		for stack := issue.CallStack; stack != nil; stack = stack.CallStack {
+			assert.NotEqual(t, path, stack.AbsPath)
+			require.NotNil(t, stack.Disassembly)
+			assert.Empty(t, stack.Disassembly.File)
+			assert.Zero(t, stack.Disassembly.Line)
+			assert.NotEmpty(t, stack.Disassembly.Address)
+		}
+	}
+}
+
+// frames lists the severity, message and the source location of every frame of the issues,
+// which do not depend on the disassembly the call stacks point into.
+func frames(issues []*analyzer.Issue) []string {
+	result := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		frame := fmt.Sprintf("%s %s", issue.Severity, issue.Message)
+		for stack := issue.CallStack; stack != nil; stack = stack.CallStack {
+			frame += fmt.Sprintf(" <- %s %s:%d", stack.Function, stack.AbsPath, stack.Line)
+		}
+		result = append(result, frame)
+	}
+	return result
+}
+
+func TestGoObjdumpMatchesNative(t *testing.T) {
+	prof, err := profile.LoadProfile("../../profile/asterisc/asterisc-64.yaml")
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "sample")
+	require.NoError(t, common.BuildBinary("../../examples/sample.go", prof.GOOS, prof.GOARCH, path, nil))
+	binary, err := os.ReadFile(path)
+	require.NoError(t, err)
+	reader, err := goobjdump.New(prof.GOOS, prof.GOARCH, nil).Disassemble(disassembler.SourceBinary, path)
+	require.NoError(t, err)
+	disassembly, err := io.ReadAll(reader)
+	require.NoError(t, err)
+
+	native, err := NewAssemblySyscallAnalyser(prof).Analyze(&analyzer.Program{Path: path, Disassembly: binary, Binary: binary}, true)
+	require.NoError(t, err)
+	issues, err := NewAssemblySyscallAnalyser(prof).Analyze(&analyzer.Program{Path: path, Disassembly: disassembly, Binary: binary}, true)
+	require.NoError(t, err)
+	assert.NotEmpty(t, issues)
+	assert.ElementsMatch(t, frames(native), frames(issues))
+}
+
+func TestObjdumpMatchesNative(t *testing.T) {
+	if _, err := exec.LookPath("llvm-objdump"); err != nil {
+		t.Skip("llvm-objdump is not installed")
+	}
+	prof, err := profile.LoadProfile("../../profile/cannon/cannon-multithreaded-32.yaml")
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "sample")
+	require.NoError(t, common.BuildBinary("../../examples/sample.go", prof.GOOS, prof.GOARCH, path, nil))
+	binary, err := os.ReadFile(path)
+	require.NoError(t, err)
+	// llvm-objdump prints runs of zero words as "...", they may hold the delay slot of a branch
+	reader, err := objdump.New(prof.GOOS, prof.GOARCH, nil).Disassemble(disassembler.SourceBinary, path)
+	require.NoError(t, err)
+	disassembly, err := io.ReadAll(reader)
+	require.NoError(t, err)
+
+	native, err := NewAssemblySyscallAnalyser(prof).Analyze(&analyzer.Program{Path: path, Disassembly: binary, Binary: binary}, true)
+	require.NoError(t, err)
+	issues, err := NewAssemblySyscallAnalyser(prof).Analyze(&analyzer.Program{Path: path, Disassembly: disassembly, Binary: binary}, true)
+	require.NoError(t, err)
+	assert.NotEmpty(t, issues)
+	assert.ElementsMatch(t, frames(native), frames(issues))
+}
@@ -0,0 +1,152 @@
+package syscall
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ChainSafe/vm-compat/analyzer"
+	"github.com/ChainSafe/vm-compat/common"
+	"github.com/ChainSafe/vm-compat/profile"
+)
+
+const (
+	asmOnlyImpactMsg = `The assembly analysis found this syscall but the SSA analysis did not, for instance
+             because it is made by assembly code or reached through calls missing from the SSA call graph.`
+	ssaOnlyImpactMsg = `The SSA analysis found this syscall but the assembly analysis did not, for instance
+             because the call was removed by the compiler or is reached through an unresolved indirect call.`
+)
+
+// crossSyscallAnalyser runs both the assembly and the SSA syscall analyzers on a program and
+// reports where their findings disagree.
+type crossSyscallAnalyser struct {
+	asm *asmSyscallAnalyser
+	ssa *goSyscallAnalyser
+}
+
+// NewCrossSyscallAnalyser initializes an analyser comparing the syscalls found in the disassembly
+// of a program with the syscalls found in its Go source.
+func NewCrossSyscallAnalyser(profile *profile.VMProfile, build *common.BuildConfig) analyzer.Analyzer {
+	return &crossSyscallAnalyser{
+		asm: &asmSyscallAnalyser{profile: profile},
+		ssa: &goSyscallAnalyser{profile: profile, build: build},
+	}
+}
+
+// Analyze matches the syscalls of both analyzers by number and by the function setting the number,
+// regardless of the profile. Every syscall only one analyzer found is reported as a warning, along
+// with the numbers, calls and packages either analyzer could not resolve, which explain most of them.
+// The disassembly is analyzed from program, the Go packages are loaded from program.Path.
+func (a *crossSyscallAnalyser) Analyze(program *analyzer.Program, withTrace bool) ([]*analyzer.Issue, error) {
+	asmFindings, asmIssues, err := a.asm.findSyscalls(program, withTrace, func(int) bool { return true })
+	if err != nil {
+		return nil, fmt.Errorf("assembly analysis failed: %w", err)
+	}
+	ssaFindings, ssaIssues, err := a.ssa.findSyscalls(&analyzer.Program{Path: program.Path}, withTrace)
+	if err != nil {
+		return nil, fmt.Errorf("SSA analysis failed: %w", err)
+	}
+
+	// Only the issues of the SSA analysis depend on its call graph
+	issues := append(asmIssues, a.ssa.recordAlgorithm(ssaIssues)...)
+	issues = append(issues, disagreements(asmFindings, ssaFindings, "Assembly", "SSA", asmOnlyImpactMsg, withTrace)...)
+	ssaOnly := disagreements(ssaFindings, asmFindings, "SSA", "Assembly", ssaOnlyImpactMsg, withTrace)
+	return append(issues, a.ssa.recordAlgorithm(ssaOnly)...), nil
+}
+
+// disagreements reports the findings missing from others, once per number and function. The message
+// tells whether the other analyzer found the number in another function or not at all.
+func disagreements(findings, others []*syscallFinding, name, other, impact string, withTrace bool) []*analyzer.Issue {
+	type key struct {
+		num      int
+		function string
+	}
+	found := make(map[key]bool)
+	numbers := make(map[int]bool)
+	for _, finding := range others {
+		found[key{finding.num, normalizeFunction(finding.function)}] = true
+		numbers[finding.num] = true
+	}
+	issues := make([]*analyzer.Issue, 0)
+	for _, finding := range findings {
+		k := key{finding.num, normalizeFunction(finding.function)}
+		if found[k] {
+			continue
+		}
+		found[k] = true // Report each number and function once
+		message := fmt.Sprintf("Syscall Only Found In %s: %d in %s", name, finding.num, k.function)
+		if !numbers[finding.num] {
+			message = fmt.Sprintf("Syscall Number Only Found In %s: %d in %s (never found by %s)", name, finding.num, k.function, other)
+		}
+		source := finding.source
+		if !withTrace && source != nil {
+			source.CallStack = nil
+		}
+		issues = append(issues, &analyzer.Issue{
+			Severity:  analyzer.IssueSeverityWarning,
+			Message:   message,
+			CallStack: source,
+			Impact:    impact,
+			Reference: analyzerWorkingPrincipalURL,
+		})
+	}
+	return issues
+}
+
+// TraceStack generates the call stack of a function in the disassembly.
+func (a *crossSyscallAnalyser) TraceStack(program *analyzer.Program, function string) (*analyzer.CallStack, error) {
+	return a.asm.TraceStack(program, function)
+}
+
+// normalizeFunction converts a function name of the SSA form or of the symbols of a binary to
+// a common form. Type arguments are elided, methods are named pkg.(*T).M and closures f.func1.
+func normalizeFunction(name string) string {
+	name = elideTypeArguments(name)
+	// SSA names methods (*pkg.T).M and (pkg.T).M
+	if end := strings.Index(name, ")"); strings.HasPrefix(name, "(") && end > 0 {
+		receiver, method := name[1:end], name[end+1:]
+		pointer := strings.HasPrefix(receiver, "*")
+		receiver = strings.TrimPrefix(receiver, "*")
+		slash := strings.LastIndex(receiver, "/")
+		if dot := strings.Index(receiver[slash+1:], "."); dot >= 0 {
+			pkg, typ := receiver[:slash+1+dot], receiver[slash+1+dot+1:]
+			if pointer {
+				typ = "(*" + typ + ")"
+			}
+			name = pkg + "." + typ + method
+		}
+	}
+	// SSA names closures f$1, nested ones f$1$1 and method values (*T).M$bound
+	parts := strings.Split(name, "$")
+	name = parts[0]
+	for i, part := range parts[1:] {
+		switch {
+		case part == "bound":
+			name += "-fm"
+		case i == 0:
+			name += ".func" + part
+		default:
+			name += "." + part
+		}
+	}
+	return name
+}
+
+// elideTypeArguments replaces the type arguments of a name by [...], as in the line table of a binary.
+func elideTypeArguments(name string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range name {
+		switch {
+		case r == '[':
+			if depth == 0 {
+				b.WriteString("[...]")
+			}
+			depth++
+		case r == ']' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package syscall
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeFunction(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "syscall.lstat", want: "syscall.lstat"},
+		{name: "(*os.File).Read", want: "os.(*File).Read"},
+		{name: "(internal/poll.FD).Init", want: "internal/poll.FD.Init"},
+		{name: "(*golang.org/x/net/http2.Framer).ReadFrame", want: "golang.org/x/net/http2.(*Framer).ReadFrame"},
+		{name: "os.lstatNolog$1", want: "os.lstatNolog.func1"},
+		{name: "sync.OnceValue[error]$1$1", want: "sync.OnceValue[...].func1.1"},
+		{name: "sync.OnceValue[go.shape.interface { Error() string }].func1.1", want: "sync.OnceValue[...].func1.1"},
+		{name: "(*runtime.itabTableType).add$bound", want: "runtime.(*itabTableType).add-fm"},
+		{name: "(*sync.Map[map[string]int]).Load", want: "sync.(*Map[...]).Load"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, normalizeFunction(tt.name), tt.name)
+	}
+}
+
+func TestDisagreements(t *testing.T) {
+	asm := []*syscallFinding{
+		{num: 5006, function: "syscall.lstat"},
+		{num: 5000, function: "runtime.read"},
+		{num: 5000, function: "runtime.read"},
+		{num: 5222, function: "runtime.nanotime1"},
+	}
+	ssa := []*syscallFinding{
+		{num: 5006, function: "syscall.lstat"},
+		{num: 5000, function: "syscall.read"},
+		{num: 5004, function: "(*os.File).Stat"},
+	}
+	messages := func(findings, others []*syscallFinding, name, other string) []string {
+		result := make([]string, 0)
+		for _, issue := range disagreements(findings, others, name, other, "", false) {
+			result = append(result, issue.Message)
+		}
+		return result
+	}
+	assert.Equal(t, []string{
+		"Syscall Only Found In Assembly: 5000 in runtime.read",
+		"Syscall Number Only Found In Assembly: 5222 in runtime.nanotime1 (never found by SSA)",
+	}, messages(asm, ssa, "Assembly", "SSA"))
+	assert.Equal(t, []string{
+		"Syscall Only Found In SSA: 5000 in syscall.read",
+		"Syscall Number Only Found In SSA: 5004 in os.(*File).Stat (never found by Assembly)",
+	}, messages(ssa, asm, "SSA", "Assembly"))
+}
@@ -0,0 +1,42 @@
+package syscall
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/ChainSafe/vm-compat/analyzer"
+	"github.com/ChainSafe/vm-compat/common"
+	"github.com/ChainSafe/vm-compat/profile"
+)
+
+// syscallFinding is a syscall detected by an analyzer.
+type syscallFinding struct {
+	num      int
+	function string              // Function setting the number, as named by the analyzer.
+	source   *analyzer.CallStack // Full call stack of the syscall.
+}
+
+// syscallIssue reports a syscall that the profile does not allow. It is critical unless the
+// profile marks it as a NOOP or it is only reached through ignored functions.
+func syscallIssue(prof *profile.VMProfile, finding *syscallFinding, withTrace bool) *analyzer.Issue {
+	severity := analyzer.IssueSeverityCritical
+	if common.ShouldIgnoreSource(finding.source, prof.IgnoredFunctions) {
+		severity = analyzer.IssueSeverityWarning
+	}
+	message := fmt.Sprintf("Potential Incompatible Syscall Detected: %d", finding.num)
+	if slices.Contains(prof.NOOPSyscalls, finding.num) {
+		message = fmt.Sprintf("Potential NOOP Syscall Detected: %d", finding.num)
+		severity = analyzer.IssueSeverityWarning
+	}
+	source := finding.source
+	if !withTrace && source != nil {
+		source.CallStack = nil
+	}
+	return &analyzer.Issue{
+		Severity:  severity,
+		Message:   message,
+		CallStack: source,
+		Impact:    potentialImpactMsg,
+		Reference: analyzerWorkingPrincipalURL,
+	}
+}
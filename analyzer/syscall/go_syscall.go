@@ -2,11 +2,10 @@ package syscall
 
 import (
 	"fmt"
+	"go/constant"
 	"go/token"
-	"os"
 	"path/filepath"
 	"slices"
-	"strconv"
 	"strings"
 
 	"github.com/ChainSafe/vm-compat/analyzer"
@@ -14,67 +13,140 @@ import (
 	"github.com/ChainSafe/vm-compat/common/lifo"
 	"github.com/ChainSafe/vm-compat/profile"
 	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
 	"golang.org/x/tools/go/callgraph/rta"
+	"golang.org/x/tools/go/callgraph/vta"
 	"golang.org/x/tools/go/packages"
 	"golang.org/x/tools/go/ssa"
 	"golang.org/x/tools/go/ssa/ssautil"
 )
 
-var syscallAPIs = []string{
-	"syscall.RawSyscall6",
-	"syscall.rawSyscallNoError",
-	"syscall.rawVforkSyscall",
-	"syscall.runtime_doAllThreadsSyscall",
+// defaultSyscallAPIs are the functions of the standard library and golang.org/x/sys making syscalls,
+// all of them take the syscall number as first argument. The functions implemented in assembly
+// have no body in SSA, so the syscalls made through them are only found when they are listed.
+var defaultSyscallAPIs = []profile.SyscallAPI{
+	{Function: "syscall.Syscall"},
+	{Function: "syscall.Syscall6"},
+	{Function: "syscall.RawSyscall"},
+	{Function: "syscall.RawSyscall6"},
+	{Function: "syscall.rawSyscallNoError"},
+	{Function: "syscall.rawVforkSyscall"},
+	{Function: "syscall.runtime_doAllThreadsSyscall"},
+	{Function: "runtime/internal/syscall.Syscall6"},
+	{Function: "internal/runtime/syscall.Syscall6"},
+	{Function: "internal/runtime/syscall/linux.Syscall6"},
+	{Function: "golang.org/x/sys/unix.Syscall"},
+	{Function: "golang.org/x/sys/unix.Syscall6"},
+	{Function: "golang.org/x/sys/unix.RawSyscall"},
+	{Function: "golang.org/x/sys/unix.RawSyscall6"},
+	{Function: "golang.org/x/sys/unix.SyscallNoError"},
+	{Function: "golang.org/x/sys/unix.RawSyscallNoError"},
 }
 
+// defaultContextDepth is the number of calls telling apart the contexts of a function, enough for
+// a syscall wrapper called by another wrapper.
+const defaultContextDepth = 2
+
 // goSyscallAnalyser analyzes system calls in Go binaries.
 type goSyscallAnalyser struct {
 	profile *profile.VMProfile
+	build   *common.BuildConfig
 }
 
 // NewGOSyscallAnalyser initializes an analyser for Go syscalls.
-func NewGOSyscallAnalyser(profile *profile.VMProfile) analyzer.Analyzer {
-	return &goSyscallAnalyser{profile: profile}
+// The build configuration is applied when loading the packages of the program.
+func NewGOSyscallAnalyser(profile *profile.VMProfile, build *common.BuildConfig) analyzer.Analyzer {
+	return &goSyscallAnalyser{profile: profile, build: build}
 }
 
-// Analyze scans a Go binary for syscalls and detects compatibility issues.
-//
-//nolint:cyclop
-func (a *goSyscallAnalyser) Analyze(path string, withTrace bool) ([]*analyzer.Issue, error) {
-	cg, fset, err := a.buildCallGraph(path)
+// Analyze scans the packages of a Go program for syscalls and detects compatibility issues.
+func (a *goSyscallAnalyser) Analyze(program *analyzer.Program, withTrace bool) ([]*analyzer.Issue, error) {
+	findings, issues, err := a.findSyscalls(program, withTrace)
 	if err != nil {
 		return nil, err
 	}
-	syscalls := a.extractSyscalls(cg)
-
 	// Check against allowed syscalls.
-	issues := make([]*analyzer.Issue, 0)
-	for i := range syscalls {
-		syscll := syscalls[i]
-		if slices.Contains(a.profile.AllowedSycalls, syscll.num) {
+	reported := make(map[string]bool)
+	for _, finding := range findings {
+		if slices.Contains(a.profile.AllowedSycalls, finding.num) {
 			continue
 		}
-		stackTrace := a.edgeToCallStack(syscll.edgeStack, fset, withTrace)
-
-		severity := analyzer.IssueSeverityCritical
-		message := fmt.Sprintf("Potential Incompatible Syscall Detected: %d", syscll.num)
-		if slices.Contains(a.profile.NOOPSyscalls, syscll.num) {
-			severity = analyzer.IssueSeverityWarning
-			message = fmt.Sprintf("Potential NOOP Syscall Detected: %d", syscll.num)
+		issue := syscallIssue(a.profile, finding, withTrace)
+		// Without traces, the paths of a syscall through the same call site are a single issue
+		if source := issue.CallStack; !withTrace && source != nil {
+			key := fmt.Sprintf("%s %s %s:%d %s", issue.Severity, issue.Message, source.File, source.Line, source.Function)
+			if reported[key] {
+				continue
+			}
+			reported[key] = true
 		}
+		issues = append(issues, issue)
+	}
+	return a.recordAlgorithm(issues), nil
+}
 
+// findSyscalls returns the syscalls reachable from the roots of the program, along with the issues
+// about the packages that could not be built and the syscall numbers that could not be resolved.
+func (a *goSyscallAnalyser) findSyscalls(program *analyzer.Program, withTrace bool) ([]*syscallFinding, []*analyzer.Issue, error) {
+	cg, fset, unbuilt, err := a.buildCallGraph(program.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	syscalls := a.extractSyscalls(cg)
+
+	issues := make([]*analyzer.Issue, 0)
+	for _, pkg := range unbuilt {
 		issues = append(issues, &analyzer.Issue{
-			Severity:  severity,
-			CallStack: stackTrace,
-			Message:   message,
+			Severity: analyzer.IssueSeverityWarning,
+			Message:  fmt.Sprintf("Unbuilt Package: %s (%s)", pkg.pkg.Pkg.Path(), pkg.reason),
+			Impact:   unbuiltPackageImpactMsg,
+		})
+	}
+	findings := make([]*syscallFinding, 0, len(syscalls))
+	for _, syscll := range syscalls {
+		if syscll.unresolved != nil {
+			issues = append(issues, a.unresolvedSyscall(syscll, fset, withTrace))
+			continue
+		}
+		findings = append(findings, &syscallFinding{
+			num:      syscll.num,
+			function: functionName(syscll.function),
+			source:   a.edgeToCallStack(syscll.edgeStack, fset, true),
 		})
 	}
+	return findings, issues, nil
+}
+
+// functionName returns the name of fn, with the path of a main package replaced by main as in
+// the symbols of a binary.
+func functionName(fn *ssa.Function) string {
+	if fn == nil {
+		return ""
+	}
+	if pkg := fn.Package(); pkg != nil && pkg.Pkg.Name() == "main" {
+		return strings.ReplaceAll(fn.String(), pkg.Pkg.Path()+".", "main.")
+	}
+	return fn.String()
+}
 
-	return issues, nil
+// unresolvedSyscall reports a syscall whose number could not be resolved, at the position of the
+// SSA value it was lost at, or of the function holding the value when it has no position.
+func (a *goSyscallAnalyser) unresolvedSyscall(syscll *syscallSource, fset *token.FileSet, withTrace bool) *analyzer.Issue {
+	pos := syscll.unresolved.Pos()
+	if fn := syscll.unresolved.Parent(); !pos.IsValid() && fn != nil {
+		pos = fn.Pos()
+	}
+	return &analyzer.Issue{
+		Severity:  analyzer.IssueSeverityWarning,
+		CallStack: a.edgeToCallStack(syscll.edgeStack, fset, withTrace),
+		Message: fmt.Sprintf("Unresolved Syscall Number: %s (%s) at %s",
+			syscll.reason, syscll.unresolved.String(), fset.Position(pos)),
+		Impact: unresolvedSyscallImpactMsg,
+	}
 }
 
-func (a *goSyscallAnalyser) TraceStack(path string, function string) (*analyzer.CallStack, error) {
-	cg, fset, err := a.buildCallGraph(path)
+func (a *goSyscallAnalyser) TraceStack(program *analyzer.Program, function string) (*analyzer.CallStack, error) {
+	cg, fset, _, err := a.buildCallGraph(program.Path)
 	if err != nil {
 		return nil, err
 	}
@@ -85,10 +157,38 @@ func (a *goSyscallAnalyser) TraceStack(path string, function string) (*analyzer.
 	return sources[function], nil
 }
 
+// syscallAPIs returns the argument holding the syscall number of every syscall API, keyed by the
+// name of the function. The APIs of the profile are added to the defaults, or replace them.
+func (a *goSyscallAnalyser) syscallAPIs() map[string]int {
+	apis := make(map[string]int)
+	for _, api := range slices.Concat(defaultSyscallAPIs, a.profile.SyscallAPIs) {
+		apis[api.Function] = api.NumberArg
+	}
+	return apis
+}
+
+// contextDepth returns the number of calls telling apart the contexts of a function.
+func (a *goSyscallAnalyser) contextDepth() int {
+	if a.profile.ContextDepth == 0 {
+		return defaultContextDepth
+	}
+	return a.profile.ContextDepth
+}
+
+// callString identifies the context of a function by the last calls leading to it.
+type callString [profile.MaxContextDepth]*callgraph.Edge
+
+// extractSyscalls walks the call paths from the roots to the syscall APIs and resolves the numbers
+// passed along each of them. A function is walked once per call string of the last k calls leading
+// to it, so the syscalls of a wrapper are resolved for every caller up to k levels above it, each
+// with the call path it is reached by. Callers further up sharing the same k calls are only walked
+// through the first of them.
 func (a *goSyscallAnalyser) extractSyscalls(cg *callgraph.Graph) []*syscallSource {
+	apis := a.syscallAPIs()
+	depth := a.contextDepth()
 	sources := make([]*lifo.Stack[*callgraph.Edge], 0)
 	currentStack := lifo.Stack[*callgraph.Edge]{}
-	seen := make(map[*callgraph.Edge]bool)
+	seen := make(map[callString]bool)
 
 	var visit func(n *callgraph.Node, edge *callgraph.Edge)
 
@@ -97,12 +197,17 @@ func (a *goSyscallAnalyser) extractSyscalls(cg *callgraph.Graph) []*syscallSourc
 			currentStack.Push(edge)
 		}
 
-		if edge != nil && edge.Callee != nil && slices.Contains(syscallAPIs, edge.Callee.Func.String()) {
+		if _, ok := apis[functionOf(edge)]; ok {
 			sources = append(sources, currentStack.Copy())
 		} else {
-			seen[edge] = true
 			for _, e := range n.Out {
-				if !seen[e] {
+				currentStack.Push(e)
+				var context callString
+				copy(context[:], currentStack.Last(depth))
+				currentStack.Pop()
+				// Every call of a syscall API is recorded, it is the end of the path
+				if _, ok := apis[functionOf(e)]; ok || !seen[context] {
+					seen[context] = true
 					visit(e.Callee, e)
 				}
 			}
@@ -113,17 +218,27 @@ func (a *goSyscallAnalyser) extractSyscalls(cg *callgraph.Graph) []*syscallSourc
 	}
 
 	for _, n := range cg.Nodes {
-		if isRoot(n.Func.String()) {
+		if isRoot(n.Func) {
 			visit(n, nil)
 		}
 	}
 
 	syscalls := make([]*syscallSource, 0)
 	for _, stack := range sources {
+		// The number is an argument of the syscall API, resolved in the context of its caller
 		edge, _ := stack.Peek() // It must be a syscall API
-		calls := resolveSyscallValue(edge.Site.Common().Args[0], stack)
+		var calls []*syscallSource
+		if number := numberArg(edge, apis[functionOf(edge)]); number != nil {
+			calls = resolveSyscallValue(number, stack)
+		} else if call := edge.Site.Value(); call != nil {
+			calls = unresolvedValue(call, "syscall API without number argument")
+		} else { // The API is started by a go or defer statement
+			calls = unresolvedValue(edge.Site.Common().Value, "syscall API without number argument")
+		}
 		for _, call := range calls {
-			call.edgeStack = stack
+			if call.edgeStack == nil {
+				call.edgeStack = stack
+			}
 		}
 		syscalls = append(syscalls, calls...)
 	}
@@ -131,8 +246,34 @@ func (a *goSyscallAnalyser) extractSyscalls(cg *callgraph.Graph) []*syscallSourc
 	return syscalls
 }
 
+// functionOf returns the name of the function called by edge, or an empty name.
+func functionOf(edge *callgraph.Edge) string {
+	if edge == nil || edge.Callee == nil || edge.Callee.Func == nil {
+		return ""
+	}
+	return edge.Callee.Func.String()
+}
+
+// numberArg returns the argument at index of the call of edge. The receiver of a method is its
+// first argument, also when it is called through an interface.
+func numberArg(edge *callgraph.Edge, index int) ssa.Value {
+	call := edge.Site.Common()
+	if call.IsInvoke() {
+		if index == 0 {
+			return call.Value
+		}
+		index--
+	}
+	if index >= len(call.Args) {
+		return nil
+	}
+	return call.Args[index]
+}
+
 func (a *goSyscallAnalyser) edgeToCallStack(stack *lifo.Stack[*callgraph.Edge], fset *token.FileSet, fullStack bool) *analyzer.CallStack {
-	var issueSource *analyzer.CallStack
+	// The innermost call site comes first, followed by its callers, as in the assembly call stacks
+	var issueSource, caller *analyzer.CallStack
+	stack = stack.Copy()
 	for !stack.IsEmpty() {
 		edge, _ := stack.Pop()
 		if edge.Site == nil {
@@ -145,10 +286,12 @@ func (a *goSyscallAnalyser) edgeToCallStack(stack *lifo.Stack[*callgraph.Edge],
 			Function: edge.Caller.Func.String(),
 			AbsPath:  filepath.Clean(position.Filename),
 		}
-		if issueSource != nil {
-			src.CallStack = issueSource
+		if caller != nil {
+			caller.CallStack = src
+		} else {
+			issueSource = src
 		}
-		issueSource = src
+		caller = src
 		if !fullStack {
 			return issueSource
 		}
@@ -196,7 +339,7 @@ func (a *goSyscallAnalyser) buildCallStack(cg *callgraph.Graph, fset *token.File
 	}
 
 	for _, n := range cg.Nodes {
-		if isRoot(n.Func.String()) {
+		if isRoot(n.Func) {
 			visit(n, nil)
 		}
 	}
@@ -214,108 +357,232 @@ func (a *goSyscallAnalyser) buildCallStack(cg *callgraph.Graph, fset *token.File
 	return issuesSources
 }
 
-func (a *goSyscallAnalyser) buildCallGraph(path string) (*callgraph.Graph, *token.FileSet, error) {
+func (a *goSyscallAnalyser) buildCallGraph(path string) (*callgraph.Graph, *token.FileSet, []*unbuiltPackage, error) {
 	// Find the Go module root for correct context
-	modRoot, err := common.FindGoModuleRoot(path)
+	modRoot, pattern, err := common.PackagePattern(path)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to find Go module root: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to find Go module root: %w", err)
 	}
 	cfg := &packages.Config{
 		Mode:       packages.LoadAllSyntax,
-		BuildFlags: []string{},
+		BuildFlags: a.build.Flags(),
 		Dir:        modRoot,
-		Env: append(
-			os.Environ(),
-			fmt.Sprintf("GOOS=%s", a.profile.GOOS),
-			fmt.Sprintf("GOARCH=%s", a.profile.GOARCH),
-		),
+		Env:        a.build.Environ(a.profile.GOOS, a.profile.GOARCH),
 	}
 
-	initial, err := packages.Load(cfg, path)
+	initial, err := packages.Load(cfg, pattern)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	if packages.PrintErrors(initial) > 0 {
-		return nil, nil, fmt.Errorf("packages contain errors")
+		return nil, nil, nil, fmt.Errorf("packages contain errors")
 	}
 
 	// Create and build SSA-form program representation.
-	mode := ssa.InstantiateGenerics
+	mode := ssa.InstantiateGenerics | ssa.BuildSerially
 	prog, _ := ssautil.AllPackages(initial, mode)
-	prog.Build()
+	unbuilt := buildPackages(prog)
 
-	// Construct call graph using RTA analysis.
 	mains, err := mainPackages(prog.AllPackages())
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
-	roots := make([]*ssa.Function, 0)
-	for _, main := range mains {
-		roots = append(roots, main.Func("main"))
+	cg, err := a.callGraph(prog, mains)
+	if err != nil {
+		return nil, nil, nil, err
 	}
-	roots = append(roots, initFuncs(prog.AllPackages())...)
-
-	cg := rta.Analyze(roots, true).CallGraph
 	cg.DeleteSyntheticNodes()
 
-	return cg, initial[0].Fset, nil
+	return cg, initial[0].Fset, unbuilt, nil
+}
+
+// algorithm returns the call graph algorithm selected by the profile, RTA by default.
+func (a *goSyscallAnalyser) algorithm() string {
+	if a.profile.CallGraph == "" {
+		return profile.CallGraphRTA
+	}
+	return a.profile.CallGraph
+}
+
+// recordAlgorithm records the call graph algorithm the issues were found with.
+func (a *goSyscallAnalyser) recordAlgorithm(issues []*analyzer.Issue) []*analyzer.Issue {
+	for _, issue := range issues {
+		issue.CallGraph = a.algorithm()
+	}
+	return issues
+}
+
+// callGraph builds the call graph of the program with the selected algorithm. CHA and VTA cover
+// every function of the program, only the part reachable from the main packages is walked.
+func (a *goSyscallAnalyser) callGraph(prog *ssa.Program, mains []*ssa.Package) (*callgraph.Graph, error) {
+	switch a.algorithm() {
+	case profile.CallGraphCHA:
+		return cha.CallGraph(prog), nil
+	case profile.CallGraphRTA:
+		roots := make([]*ssa.Function, 0)
+		for _, main := range mains {
+			roots = append(roots, main.Func("main"))
+		}
+		roots = append(roots, initFuncs(prog.AllPackages())...)
+		return rta.Analyze(roots, true).CallGraph, nil
+	case profile.CallGraphVTA:
+		return vta.CallGraph(ssautil.AllFunctions(prog), cha.CallGraph(prog)), nil
+	}
+	return nil, fmt.Errorf("invalid call graph algorithm: %s", a.algorithm())
+}
+
+// unbuiltPackage is a package the SSA builder failed on.
+type unbuiltPackage struct {
+	pkg    *ssa.Package
+	reason string
+}
+
+// buildPackages builds the SSA of every package of the program, one at a time. The SSA builder
+// panics on syntax it does not know, such as the promoted fields in composite literals of a
+// standard library newer than golang.org/x/tools. The functions of such a package are left
+// without body, so the rest of the program can still be analyzed.
+func buildPackages(prog *ssa.Program) []*unbuiltPackage {
+	unbuilt := make([]*unbuiltPackage, 0)
+	for _, pkg := range prog.AllPackages() {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					unbuilt = append(unbuilt, &unbuiltPackage{pkg: pkg, reason: fmt.Sprint(r)})
+				}
+			}()
+			pkg.Build()
+		}()
+	}
+	if len(unbuilt) == 0 {
+		return unbuilt
+	}
+	for fn := range ssautil.AllFunctions(prog) {
+		for _, pkg := range unbuilt {
+			if fn.Package() == pkg.pkg {
+				fn.Blocks = nil
+			}
+		}
+	}
+	slices.SortFunc(unbuilt, func(a, b *unbuiltPackage) int {
+		return strings.Compare(a.pkg.Pkg.Path(), b.pkg.Pkg.Path())
+	})
+	return unbuilt
 }
 
 type syscallSource struct {
-	num       int
+	num int
+	// edgeStack is the path the number is resolved along: the calls leading to the syscall API,
+	// followed by the calls entered to resolve the number from the results of callees.
 	edgeStack *lifo.Stack[*callgraph.Edge]
+	function  *ssa.Function // Function the number is set in.
+	// unresolved is the value the number could not be resolved from, with the reason, when num is not set.
+	unresolved ssa.Value
+	reason     string
+}
+
+// resolveSyscallValue returns the constant numbers value may take. path holds the calls leading
+// to the syscall API, the last one calls it with value. Values that are not modeled yield an
+// unresolved source instead of a number.
+func resolveSyscallValue(value ssa.Value, path *lifo.Stack[*callgraph.Edge]) []*syscallSource {
+	r := &valueResolver{visiting: make(map[ssa.Value]bool), path: path}
+	callers := path.Copy()
+	callers.Pop()
+	return r.resolve(value, callers)
+}
+
+// valueResolver follows SSA values back to the constants they are computed from.
+type valueResolver struct {
+	visiting map[ssa.Value]bool // Values being resolved, to stop at cycles through phis and recursive calls.
+	path     *lifo.Stack[*callgraph.Edge]
+	// callees are the calls entered to resolve the results of callees, the last one is the innermost.
+	callees []*callgraph.Edge
 }
 
-func resolveSyscallValue(value ssa.Value, edgeStack *lifo.Stack[*callgraph.Edge]) []*syscallSource {
+// resolve returns the sources of value, each of them along the path the value is resolved by.
+func (r *valueResolver) resolve(value ssa.Value, edgeStack *lifo.Stack[*callgraph.Edge]) []*syscallSource {
+	if value == nil {
+		return nil
+	}
+	if r.visiting[value] { // The other edges of the cycle provide the values
+		return nil
+	}
+	r.visiting[value] = true
+	defer delete(r.visiting, value)
+
+	result := r.resolveValue(value, edgeStack)
+	for _, source := range result {
+		if source.edgeStack == nil { // The source is found in this context
+			source.edgeStack = r.path.Copy()
+			for _, callee := range r.callees {
+				source.edgeStack.Push(callee)
+			}
+		}
+	}
+	return result
+}
+
+//nolint:cyclop
+func (r *valueResolver) resolveValue(value ssa.Value, edgeStack *lifo.Stack[*callgraph.Edge]) []*syscallSource {
 	result := make([]*syscallSource, 0)
 	switch v := value.(type) {
 	case *ssa.Const:
-		valInt, err := strconv.Atoi(v.Value.String())
-		if err == nil {
-			return []*syscallSource{{num: valInt, edgeStack: edgeStack.Copy()}}
+		if v.Value == nil || v.Value.Kind() != constant.Int {
+			return unresolvedValue(v, "non integer constant")
 		}
+		num, _ := constant.Int64Val(v.Value)
+		source := &syscallSource{num: int(num)}
+		if edge, ok := edgeStack.Peek(); ok && edge.Callee != nil { // The last call enters the function using the constant
+			source.function = edge.Callee.Func
+		}
+		return []*syscallSource{source}
 	case *ssa.Global:
-		// Iterate through instructions in the Init function
 		// Iterate through all functions in the package to find the initialization
-		for _, member := range v.Pkg.Members {
-			if fn, ok := member.(*ssa.Function); ok {
-				for _, block := range fn.Blocks {
-					for _, instr := range block.Instrs {
-						// Look for Store instructions
-						if store, ok := instr.(*ssa.Store); ok {
-							if store.Addr == v {
-								result = append(result, resolveSyscallValue(store.Val, edgeStack)...)
-							}
-						}
-					}
-				}
-			}
+		for _, store := range globalStores(v) {
+			result = append(result, r.resolve(store.Val, edgeStack)...)
+		}
+		if len(result) == 0 {
+			return unresolvedValue(v, "global without assignment")
 		}
 	case *ssa.Parameter:
-		cpStack := edgeStack.Copy()
-		prev, _ := cpStack.Pop()
-		result = append(result, resolveSyscallValue(prev.Site.Common().Args[0], cpStack)...)
+		return r.resolveParameter(v, edgeStack)
 	case *ssa.Phi:
 		for _, val := range v.Edges {
-			result = append(result, resolveSyscallValue(val, edgeStack)...) // TODO: debug
+			result = append(result, r.resolve(val, edgeStack)...)
 		}
 	case *ssa.Call:
-		// Trace nested calls
-		fn := v.Call.StaticCallee()
-		for _, block := range fn.Blocks {
-			for _, instr := range block.Instrs {
-				// Look for return instructions
-				if ret, ok := instr.(*ssa.Return); ok {
-					for _, val := range ret.Results {
-						result = append(result, resolveSyscallValue(val, edgeStack)...)
-					}
-				}
+		return r.resolveResult(v, 0, edgeStack)
+	case *ssa.Extract:
+		switch tuple := v.Tuple.(type) {
+		case *ssa.Call:
+			return r.resolveResult(tuple, v.Index, edgeStack)
+		case *ssa.Lookup, *ssa.TypeAssert: // The value of a comma-ok pair
+			if v.Index == 0 {
+				return r.resolve(tuple, edgeStack)
 			}
 		}
+		return unresolvedValue(v, "unsupported tuple")
+	case *ssa.BinOp:
+		return r.resolveBinOp(v, edgeStack)
+	case *ssa.Lookup:
+		return r.resolveLookup(v, edgeStack)
 	case *ssa.UnOp:
-		result = append(result, resolveSyscallValue(v.X, edgeStack)...)
+		switch v.Op {
+		case token.MUL: // Load from the address
+			return r.resolve(v.X, edgeStack)
+		case token.SUB:
+			return mapNumbers(r.resolve(v.X, edgeStack), func(x int) int { return -x })
+		case token.XOR:
+			return mapNumbers(r.resolve(v.X, edgeStack), func(x int) int { return ^x })
+		}
+		return unresolvedValue(v, "unsupported operator "+v.Op.String())
+	case *ssa.MakeInterface:
+		result = append(result, r.resolve(v.X, edgeStack)...)
+	case *ssa.TypeAssert:
+		result = append(result, r.resolve(v.X, edgeStack)...)
+	case *ssa.ChangeType:
+		result = append(result, r.resolve(v.X, edgeStack)...)
 	case *ssa.Convert:
-		result = append(result, resolveSyscallValue(v.X, edgeStack)...)
+		result = append(result, r.resolve(v.X, edgeStack)...)
 	case *ssa.FieldAddr:
 		// check all instructions to get the latest value store for this field address
 		var val ssa.Value
@@ -328,14 +595,200 @@ func resolveSyscallValue(value ssa.Value, edgeStack *lifo.Stack[*callgraph.Edge]
 				}
 			}
 		}
-		result = append(result, resolveSyscallValue(val, edgeStack)...)
+		if val == nil {
+			return unresolvedValue(v, "field without assignment")
+		}
+		result = append(result, r.resolve(val, edgeStack)...)
 	default:
-		fmt.Printf("Unhandled value type: %T\n", v)
-		panic("not handled")
+		return unresolvedValue(v, fmt.Sprintf("unsupported value %T", v))
+	}
+	return result
+}
+
+// resolveParameter resolves a parameter from the argument passed by the last call of edgeStack.
+func (r *valueResolver) resolveParameter(v *ssa.Parameter, edgeStack *lifo.Stack[*callgraph.Edge]) []*syscallSource {
+	callers := edgeStack.Copy()
+	edge, ok := callers.Pop()
+	if !ok || edge.Site == nil || edge.Callee == nil || edge.Callee.Func != v.Parent() {
+		return unresolvedValue(v, "parameter without caller")
+	}
+	index := slices.Index(v.Parent().Params, v)
+	call := edge.Site.Common()
+	if call.IsInvoke() { // The receiver is not among the arguments
+		index--
+	}
+	if index < 0 || index >= len(call.Args) {
+		return unresolvedValue(v, "parameter without argument")
+	}
+	return r.resolve(call.Args[index], callers)
+}
+
+// resolveResult resolves the result at index of a static call from the return statements of the callee.
+func (r *valueResolver) resolveResult(call *ssa.Call, index int, edgeStack *lifo.Stack[*callgraph.Edge]) []*syscallSource {
+	fn := call.Call.StaticCallee()
+	if fn == nil {
+		return unresolvedValue(call, "dynamic call")
+	}
+	if len(fn.Blocks) == 0 {
+		return unresolvedValue(call, "call to a function without body")
+	}
+	// The parameters of the callee are resolved from the arguments of this call
+	edge := &callgraph.Edge{Caller: &callgraph.Node{Func: call.Parent()}, Site: call, Callee: &callgraph.Node{Func: fn}}
+	callees := edgeStack.Copy()
+	callees.Push(edge)
+	r.callees = append(r.callees, edge)
+	defer func() { r.callees = r.callees[:len(r.callees)-1] }()
+	result := make([]*syscallSource, 0)
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			// Look for return instructions
+			if ret, ok := instr.(*ssa.Return); ok && index < len(ret.Results) {
+				result = append(result, r.resolve(ret.Results[index], callees)...)
+			}
+		}
+	}
+	return result
+}
+
+// resolveBinOp applies an arithmetic operator to every pair of numbers of its operands.
+func (r *valueResolver) resolveBinOp(v *ssa.BinOp, edgeStack *lifo.Stack[*callgraph.Edge]) []*syscallSource {
+	xs, ys := r.resolve(v.X, edgeStack), r.resolve(v.Y, edgeStack)
+	result := make([]*syscallSource, 0, len(xs)*len(ys))
+	for _, operands := range [][]*syscallSource{xs, ys} {
+		for _, operand := range operands {
+			if operand.unresolved != nil {
+				result = append(result, operand)
+			}
+		}
+	}
+	for _, x := range xs {
+		for _, y := range ys {
+			if x.unresolved != nil || y.unresolved != nil {
+				continue
+			}
+			num, ok := applyBinOp(v.Op, x.num, y.num)
+			if !ok {
+				return append(result, unresolvedValue(v, "unsupported operator "+v.Op.String())...)
+			}
+			result = append(result, &syscallSource{num: num, edgeStack: x.edgeStack, function: x.function})
+		}
 	}
 	return result
 }
 
+// applyBinOp computes x op y for the arithmetic and bitwise operators.
+func applyBinOp(op token.Token, x, y int) (int, bool) {
+	switch op {
+	case token.ADD:
+		return x + y, true
+	case token.SUB:
+		return x - y, true
+	case token.MUL:
+		return x * y, true
+	case token.QUO:
+		return x / y, y != 0
+	case token.REM:
+		return x % y, y != 0
+	case token.AND:
+		return x & y, true
+	case token.OR:
+		return x | y, true
+	case token.XOR:
+		return x ^ y, true
+	case token.AND_NOT:
+		return x &^ y, true
+	case token.SHL:
+		return x << y, y >= 0
+	case token.SHR:
+		return x >> y, y >= 0
+	}
+	return 0, false
+}
+
+// resolveLookup resolves an element of a map from the updates of the map, restricted to the
+// updates of the same key when both keys are constant.
+func (r *valueResolver) resolveLookup(v *ssa.Lookup, edgeStack *lifo.Stack[*callgraph.Edge]) []*syscallSource {
+	updates, ok := mapUpdates(v.X)
+	if !ok {
+		return unresolvedValue(v, "lookup in an untracked map or string")
+	}
+	key, constKey := v.Index.(*ssa.Const)
+	result := make([]*syscallSource, 0)
+	for _, update := range updates {
+		if updateKey, ok := update.Key.(*ssa.Const); ok && constKey && !constant.Compare(key.Value, token.EQL, updateKey.Value) {
+			continue
+		}
+		result = append(result, r.resolve(update.Value, edgeStack)...)
+	}
+	if len(result) == 0 {
+		return unresolvedValue(v, "lookup of a key without assignment")
+	}
+	return result
+}
+
+// mapUpdates returns the updates of the map m, created by a map literal or make and possibly
+// stored in a global variable.
+func mapUpdates(m ssa.Value) ([]*ssa.MapUpdate, bool) {
+	switch m := m.(type) {
+	case *ssa.MakeMap:
+		updates := make([]*ssa.MapUpdate, 0)
+		for _, instr := range *m.Referrers() {
+			if update, ok := instr.(*ssa.MapUpdate); ok && update.Map == m {
+				updates = append(updates, update)
+			}
+		}
+		return updates, true
+	case *ssa.UnOp:
+		global, ok := m.X.(*ssa.Global)
+		if m.Op != token.MUL || !ok {
+			return nil, false
+		}
+		updates := make([]*ssa.MapUpdate, 0)
+		for _, store := range globalStores(global) {
+			stored, ok := mapUpdates(store.Val)
+			if !ok {
+				return nil, false
+			}
+			updates = append(updates, stored...)
+		}
+		return updates, true
+	}
+	return nil, false
+}
+
+// globalStores returns the stores to a global variable in the functions of its package.
+func globalStores(global *ssa.Global) []*ssa.Store {
+	stores := make([]*ssa.Store, 0)
+	for _, member := range global.Pkg.Members {
+		if fn, ok := member.(*ssa.Function); ok {
+			for _, block := range fn.Blocks {
+				for _, instr := range block.Instrs {
+					// Look for Store instructions
+					if store, ok := instr.(*ssa.Store); ok && store.Addr == global {
+						stores = append(stores, store)
+					}
+				}
+			}
+		}
+	}
+	return stores
+}
+
+// mapNumbers applies f to the resolved numbers of sources.
+func mapNumbers(sources []*syscallSource, f func(int) int) []*syscallSource {
+	for _, source := range sources {
+		if source.unresolved == nil {
+			source.num = f(source.num)
+		}
+	}
+	return sources
+}
+
+// unresolvedValue returns the source of a number that could not be resolved from v.
+func unresolvedValue(v ssa.Value, reason string) []*syscallSource {
+	return []*syscallSource{{unresolved: v, reason: reason}}
+}
+
 // mainPackages returns the main packages to analyze.
 // Each resulting package is named "main" and has a main function.
 func mainPackages(pkgs []*ssa.Package) ([]*ssa.Package, error) {
@@ -371,6 +824,10 @@ func initFuncs(pkgs []*ssa.Package) []*ssa.Function {
 	return inits
 }
 
-func isRoot(function string) bool {
-	return function == "command-line-arguments.main" || function == "command-line-arguments.init"
+// isRoot reports whether fn is the main or init function of a main package.
+func isRoot(fn *ssa.Function) bool {
+	if fn == nil || fn.Pkg == nil || fn.Pkg.Pkg.Name() != "main" || fn.Parent() != nil {
+		return false
+	}
+	return fn.Name() == "main" || fn.Name() == "init"
 }
@@ -0,0 +1,292 @@
+package syscall
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/ChainSafe/vm-compat/analyzer"
+	"github.com/ChainSafe/vm-compat/common/lifo"
+	"github.com/ChainSafe/vm-compat/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+const resolveSource = `package main
+
+func main() {
+	parameter()
+	contexts()
+	extract()
+}
+
+var table = map[string]uintptr{"read": 4003, "write": 4004}
+
+var global uintptr
+
+func init() { global = 4010 }
+
+func raw(trap, a1, a2 uintptr) {}
+
+func pair() (uintptr, error) { return 4001, nil }
+
+func wrap(a1, trap uintptr) { raw(trap, a1, 0) }
+
+func extract() {
+	n, _ := pair()
+	raw(n, 0, 0)
+}
+
+func binOp(b bool) {
+	n := uintptr(4000)
+	if b {
+		n = 4002
+	}
+	raw(n+1, 0, 0)
+}
+
+func lookup() { raw(table["write"], 0, 0) }
+
+func lookupCommaOk() {
+	n, _ := table["read"]
+	raw(n, 0, 0)
+}
+
+func makeInterface() {
+	var x any = uintptr(4005)
+	raw(x.(uintptr), 0, 0)
+}
+
+func globalValue() { raw(global, 0, 0) }
+
+func parameter() { wrap(0, 4006) }
+
+func inner(a1, trap uintptr) { raw(trap, a1, 0) }
+
+func outer(trap uintptr) { inner(0, trap) }
+
+func contexts() {
+	outer(4007)
+	outer(4008)
+}
+
+func dynamicCall(f func() uintptr) { raw(f(), 0, 0) }
+
+func loop(n uintptr) {
+	for n < 4000 {
+		n++
+	}
+	raw(n, 0, 0)
+}
+`
+
+// buildSource builds the SSA form of resolveSource.
+func buildSource(t *testing.T) *ssa.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", resolveSource, 0)
+	require.NoError(t, err)
+	pkg, _, err := ssautil.BuildPackage(&types.Config{Importer: importer.Default()}, fset,
+		types.NewPackage("main", "main"), []*ast.File{file}, ssa.InstantiateGenerics)
+	require.NoError(t, err)
+	return pkg
+}
+
+func TestResolveSyscallValue(t *testing.T) {
+	pkg := buildSource(t)
+
+	// resolve returns the numbers of the syscall made by fn through raw, and the reasons of the unresolved ones
+	resolve := func(name string) ([]int, []string) {
+		fn := pkg.Func(name)
+		require.NotNil(t, fn, name)
+		stack := &lifo.Stack[*callgraph.Edge]{}
+		var site *ssa.Call
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				call, ok := instr.(*ssa.Call)
+				if !ok {
+					continue
+				}
+				switch call.Call.StaticCallee() {
+				case pkg.Func("raw"):
+					site = call
+				case pkg.Func("wrap"): // The syscall is made by the callee
+					stack.Push(&callgraph.Edge{Site: call, Callee: &callgraph.Node{Func: pkg.Func("wrap")}})
+					fn = pkg.Func("wrap")
+					site = fn.Blocks[0].Instrs[0].(*ssa.Call) //nolint:forcetypeassert
+				}
+			}
+		}
+		require.NotNil(t, site, name)
+		stack.Push(&callgraph.Edge{Caller: &callgraph.Node{Func: fn}, Site: site, Callee: &callgraph.Node{Func: pkg.Func("raw")}})
+		numbers, reasons := make([]int, 0), make([]string, 0)
+		for _, source := range resolveSyscallValue(site.Call.Args[0], stack) {
+			if source.unresolved != nil {
+				reasons = append(reasons, source.reason)
+			} else {
+				numbers = append(numbers, source.num)
+			}
+		}
+		return numbers, reasons
+	}
+
+	tests := []struct {
+		fn      string
+		numbers []int
+		reasons []string
+	}{
+		{fn: "extract", numbers: []int{4001}},
+		{fn: "binOp", numbers: []int{4001, 4003}},
+		{fn: "lookup", numbers: []int{4004}},
+		{fn: "lookupCommaOk", numbers: []int{4003}},
+		{fn: "makeInterface", numbers: []int{4005}},
+		{fn: "globalValue", numbers: []int{4010}},
+		{fn: "parameter", numbers: []int{4006}},
+		{fn: "dynamicCall", reasons: []string{"dynamic call"}},
+		// The increment in the loop is not followed, the initial value has no caller
+		{fn: "loop", reasons: []string{"parameter without caller"}},
+	}
+	for _, tt := range tests {
+		numbers, reasons := resolve(tt.fn)
+		assert.ElementsMatch(t, tt.numbers, numbers, tt.fn)
+		assert.ElementsMatch(t, tt.reasons, reasons, tt.fn)
+	}
+}
+
+func TestResolvedPath(t *testing.T) {
+	pkg := buildSource(t)
+	a := &goSyscallAnalyser{profile: &profile.VMProfile{
+		CallGraph:   profile.CallGraphCHA,
+		SyscallAPIs: []profile.SyscallAPI{{Function: "main.raw"}},
+	}}
+	cg, err := a.callGraph(pkg.Prog, []*ssa.Package{pkg})
+	require.NoError(t, err)
+
+	var found bool
+	for _, source := range a.extractSyscalls(cg) {
+		stack := source.edgeStack.Copy()
+		edge, _ := stack.Pop()
+		if edge.Caller.Func != pkg.Func("extract") {
+			continue
+		}
+		found = true
+		// The number is returned by the call of pair, made before extract calls raw with it
+		assert.Equal(t, pkg.Func("pair"), edge.Callee.Func)
+		edge, _ = stack.Pop()
+		assert.Equal(t, pkg.Func("raw"), edge.Callee.Func)
+		assert.Equal(t, pkg.Func("extract"), edge.Caller.Func)
+		for !stack.IsEmpty() {
+			edge, _ = stack.Pop()
+			assert.NotNil(t, edge.Caller)
+		}
+		assert.Equal(t, 4001, source.num)
+		assert.Equal(t, pkg.Func("pair"), source.function)
+	}
+	assert.True(t, found)
+}
+
+func TestCallGraphAlgorithm(t *testing.T) {
+	pkg := buildSource(t)
+
+	for _, algorithm := range []string{profile.CallGraphCHA, profile.CallGraphVTA} {
+		a := &goSyscallAnalyser{profile: &profile.VMProfile{CallGraph: algorithm}}
+		cg, err := a.callGraph(pkg.Prog, nil)
+		require.NoError(t, err, algorithm)
+		require.NotNil(t, cg.Nodes[pkg.Func("wrap")], algorithm)
+		callees := make([]*ssa.Function, 0)
+		for _, edge := range cg.Nodes[pkg.Func("wrap")].Out {
+			callees = append(callees, edge.Callee.Func)
+		}
+		assert.Equal(t, []*ssa.Function{pkg.Func("raw")}, callees, algorithm)
+		assert.Equal(t, algorithm, a.recordAlgorithm([]*analyzer.Issue{{}})[0].CallGraph)
+	}
+
+	_, err := (&goSyscallAnalyser{profile: &profile.VMProfile{CallGraph: "andersen"}}).callGraph(pkg.Prog, nil)
+	assert.ErrorContains(t, err, "invalid call graph algorithm: andersen")
+	assert.Equal(t, profile.CallGraphRTA, (&goSyscallAnalyser{profile: &profile.VMProfile{}}).algorithm())
+
+	// Profiles and flags are checked before any package is loaded, pointer analysis is not offered
+	assert.NoError(t, profile.ValidateCallGraph(""))
+	assert.NoError(t, profile.ValidateCallGraph(profile.CallGraphVTA))
+	assert.ErrorContains(t, profile.ValidateCallGraph("pointer"), `invalid call graph algorithm "pointer"`)
+}
+
+func TestSyscallAPIs(t *testing.T) {
+	pkg := buildSource(t)
+
+	tests := []struct {
+		api        profile.SyscallAPI
+		numbers    []int
+		unresolved []string
+	}{
+		{api: profile.SyscallAPI{Function: "main.wrap", NumberArg: 1}, numbers: []int{4006}},
+		{api: profile.SyscallAPI{Function: "main.wrap"}, numbers: []int{0}},
+		{api: profile.SyscallAPI{Function: "main.wrap", NumberArg: 2}, unresolved: []string{"syscall API without number argument"}},
+	}
+	for _, tt := range tests {
+		a := &goSyscallAnalyser{profile: &profile.VMProfile{CallGraph: profile.CallGraphCHA, SyscallAPIs: []profile.SyscallAPI{tt.api}}}
+		assert.Contains(t, a.syscallAPIs(), "syscall.RawSyscall6")
+		cg, err := a.callGraph(pkg.Prog, []*ssa.Package{pkg})
+		require.NoError(t, err)
+		numbers, unresolved := make([]int, 0), make([]string, 0)
+		for _, source := range a.extractSyscalls(cg) {
+			if source.unresolved != nil {
+				unresolved = append(unresolved, source.reason)
+			} else {
+				numbers = append(numbers, source.num)
+				assert.Equal(t, pkg.Func("parameter"), source.function)
+			}
+		}
+		assert.ElementsMatch(t, tt.numbers, numbers, tt.api)
+		assert.ElementsMatch(t, tt.unresolved, unresolved, tt.api)
+	}
+}
+
+func TestContextDepth(t *testing.T) {
+	pkg := buildSource(t)
+
+	tests := []struct {
+		depth   int
+		numbers []int
+	}{
+		// The second call of outer reaches inner through the same call, its number is missed
+		{depth: 1, numbers: []int{4007}},
+		{depth: 2, numbers: []int{4007, 4008}},
+		{depth: 0, numbers: []int{4007, 4008}}, // The default depth
+	}
+	for _, tt := range tests {
+		a := &goSyscallAnalyser{profile: &profile.VMProfile{
+			CallGraph:    profile.CallGraphCHA,
+			SyscallAPIs:  []profile.SyscallAPI{{Function: "main.raw"}},
+			ContextDepth: tt.depth,
+		}}
+		cg, err := a.callGraph(pkg.Prog, []*ssa.Package{pkg})
+		require.NoError(t, err)
+		numbers := make([]int, 0)
+		for _, source := range a.extractSyscalls(cg) {
+			if source.function != pkg.Func("contexts") {
+				continue
+			}
+			numbers = append(numbers, source.num)
+			// Each number comes with the call of outer passing it
+			stack := source.edgeStack.Copy()
+			var site *callgraph.Edge
+			for !stack.IsEmpty() {
+				site, _ = stack.Pop()
+				if site.Caller.Func == pkg.Func("contexts") {
+					break
+				}
+			}
+			call, ok := site.Site.(*ssa.Call)
+			require.True(t, ok)
+			assert.Equal(t, int64(source.num), call.Call.Args[0].(*ssa.Const).Int64(), tt.depth) //nolint:forcetypeassert
+		}
+		assert.ElementsMatch(t, tt.numbers, numbers, tt.depth)
+	}
+}
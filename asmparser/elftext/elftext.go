@@ -0,0 +1,139 @@
+// Package elftext reads the code of ELF binaries and splits it into functions, for the
+// parsers that decode the instructions of a binary directly. The functions are taken from
+// the symbol table, or from the Go line table when the binary is stripped.
+package elftext
+
+import (
+	"bufio"
+	"debug/elf"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ChainSafe/vm-compat/common/lineinfo"
+)
+
+// Text is the .text section of a binary, with the function symbols located in it.
+type Text struct {
+	Addr      uint64
+	Code      []byte
+	Functions []elf.Symbol // Sorted by address, one per address.
+}
+
+// IsELF reports whether the buffered input starts with the ELF magic number.
+func IsELF(r *bufio.Reader) bool {
+	magic, err := r.Peek(len(elf.ELFMAG))
+	return err == nil && string(magic) == elf.ELFMAG
+}
+
+// Read returns the .text section of file and its functions.
+func Read(file *elf.File) (*Text, error) {
+	text := file.Section(".text")
+	if text == nil {
+		return nil, errors.New("elf file has no .text section")
+	}
+	code, err := text.Data()
+	if err != nil {
+		return nil, fmt.Errorf("error reading .text section: %w", err)
+	}
+	symbols, err := textSymbols(file, text)
+	if err != nil {
+		return nil, err
+	}
+	return &Text{Addr: text.Addr, Code: code, Functions: symbols}, nil
+}
+
+// End returns the address right after the function at index i, the start of the next one.
+func (t *Text) End(i int) uint64 {
+	if i+1 < len(t.Functions) {
+		return t.Functions[i+1].Value
+	}
+	return t.Addr + uint64(len(t.Code))
+}
+
+// Labels returns the name of the function starting at every address, and whether the
+// binary is stripped of its symbol table. Of the symbols sharing an address, the first one with
+// a size names the function.
+func Labels(file *elf.File) (map[uint64]string, bool, error) {
+	labels := make(map[uint64]string)
+	symbols, err := file.Symbols()
+	if errors.Is(err, elf.ErrNoSymbols) {
+		funcs, err := lineinfo.Functions(file)
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to read functions of stripped binary: %w", err)
+		}
+		for _, fn := range funcs {
+			labels[fn.Entry] = fn.Name
+		}
+		return labels, true, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read elf symbols: %w", err)
+	}
+	for _, sym := range symbols {
+		if elf.ST_TYPE(sym.Info) == elf.STT_FUNC && sym.Size > 0 && labels[sym.Value] == "" {
+			labels[sym.Value] = sym.Name
+		}
+	}
+	return labels, false, nil
+}
+
+// textSymbols returns the function symbols located in the text section, sorted by address.
+// When several symbols share an address only the first one with a size is kept.
+// Binaries stripped of their symbol table fall back to the function table of the Go line table.
+func textSymbols(file *elf.File, text *elf.Section) ([]elf.Symbol, error) {
+	symbols, err := file.Symbols()
+	if errors.Is(err, elf.ErrNoSymbols) {
+		symbols, err = pclntabSymbols(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading elf symbols: %w", err)
+	}
+	funcs := make([]elf.Symbol, 0, len(symbols))
+	for _, sym := range symbols {
+		if elf.ST_TYPE(sym.Info) != elf.STT_FUNC {
+			continue
+		}
+		if sym.Value < text.Addr || sym.Value >= text.Addr+text.Size {
+			continue
+		}
+		funcs = append(funcs, sym)
+	}
+	sort.SliceStable(funcs, func(i, j int) bool {
+		return funcs[i].Value < funcs[j].Value
+	})
+
+	unique := make([]elf.Symbol, 0, len(funcs))
+	for _, sym := range funcs {
+		if last := len(unique) - 1; last >= 0 && unique[last].Value == sym.Value {
+			// Markers such as runtime.text have no size and share the address of a function
+			if unique[last].Size == 0 && sym.Size > 0 {
+				unique[last] = sym
+			}
+			continue
+		}
+		unique = append(unique, sym)
+	}
+	if len(unique) == 0 {
+		return nil, errors.New("elf file has no function symbols in .text")
+	}
+	return unique, nil
+}
+
+// pclntabSymbols converts the functions of the Go line table into function symbols.
+func pclntabSymbols(file *elf.File) ([]elf.Symbol, error) {
+	funcs, err := lineinfo.Functions(file)
+	if err != nil {
+		return nil, err
+	}
+	symbols := make([]elf.Symbol, 0, len(funcs))
+	for _, fn := range funcs {
+		symbols = append(symbols, elf.Symbol{
+			Name:  fn.Name,
+			Info:  elf.ST_INFO(elf.STB_GLOBAL, elf.STT_FUNC),
+			Value: fn.Entry,
+			Size:  fn.End - fn.Entry,
+		})
+	}
+	return symbols, nil
+}
@@ -0,0 +1,45 @@
+// Package elftexttest builds ELF files for the tests of the assembly parsers.
+package elftexttest
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// DataELF returns an ELF file for machine whose only content is data, in a .rodata section loaded at addr.
+func DataELF(t *testing.T, machine elf.Machine, order binary.ByteOrder, addr uint64, data []byte) []byte {
+	t.Helper()
+	names := []byte("\x00.rodata\x00.shstrtab\x00")
+	header := elf.Header64{
+		Type:      uint16(elf.ET_EXEC),
+		Machine:   uint16(machine),
+		Version:   uint32(elf.EV_CURRENT),
+		Ehsize:    64,
+		Shentsize: 64,
+		Shnum:     3,
+		Shstrndx:  2,
+		Shoff:     uint64(64 + len(data) + len(names)),
+	}
+	copy(header.Ident[:], elf.ELFMAG)
+	header.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	header.Ident[elf.EI_DATA] = byte(elf.ELFDATA2MSB)
+	if order == binary.LittleEndian {
+		header.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	}
+	header.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	sections := []elf.Section64{
+		{},
+		{Name: 1, Type: uint32(elf.SHT_PROGBITS), Flags: uint64(elf.SHF_ALLOC), Addr: addr, Off: 64, Size: uint64(len(data))},
+		{Name: 9, Type: uint32(elf.SHT_STRTAB), Off: uint64(64 + len(data)), Size: uint64(len(names))},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, binary.Write(&buf, order, header))
+	buf.Write(data)
+	buf.Write(names)
+	require.NoError(t, binary.Write(&buf, order, sections))
+	return buf.Bytes()
+}
@@ -0,0 +1,255 @@
+// Package indirect collects the candidate targets of indirect calls in Go binaries.
+// Go calls through a register for interface methods, whose address is loaded from the
+// fun table of an itab, and for func values, whose address is loaded from a funcval.
+// Both tables hold absolute function addresses in the data sections of the binary.
+// Jumps through a register may also dispatch through a jump table, whose entries are read
+// from the data sections once the code has been traced to the table.
+// The method tables of the Go type metadata, used by reflection, only store offsets
+// and are not followed.
+// The architecture parsers decode their instructions into the few operations the Resolver
+// follows to trace the calls and jumps through a register to these tables.
+package indirect
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"slices"
+)
+
+// Layout constants of the Go runtime type metadata (internal/abi).
+const (
+	kindInterface = 20        // abi.Interface
+	kindMask      = 1<<5 - 1  // abi.KindMask
+	maxMethods    = 1 << 12   // Upper bound of the method count of an interface, to reject garbage.
+	typeWords     = 2         // Size_ and PtrBytes of abi.Type, in pointers.
+	typeKindBytes = 4 + 1 + 2 // Hash, TFlag, Align_ and FieldAlign_ of abi.Type before Kind_.
+)
+
+// maxEntries bounds the entries read from a jump table.
+const maxEntries = 1 << 12
+
+// Targets holds the candidate targets of the indirect calls of a binary.
+// A nil Targets has no targets, so every indirect call is unresolved.
+type Targets struct {
+	ptrSize uint64
+	mem     *memory         // Data sections, holding the jump tables.
+	itabs   [][]uint64      // Fun tables of the itabs.
+	taken   map[uint64]bool // Functions whose address is stored as data or materialized by code.
+}
+
+// Read collects the targets from the data sections of an ELF binary. entries holds the entry
+// addresses of the functions of the binary, words of data are only taken for addresses among them.
+func Read(file *elf.File, entries map[uint64]bool) (*Targets, error) {
+	t := &Targets{ptrSize: 8, taken: make(map[uint64]bool)}
+	if file.Class == elf.ELFCLASS32 {
+		t.ptrSize = 4
+	}
+	mem, err := readData(file)
+	if err != nil {
+		return nil, err
+	}
+	t.mem = mem
+	for _, region := range mem.regions {
+		for off := alignUp(region.addr, t.ptrSize) - region.addr; off+t.ptrSize <= uint64(len(region.data)); off += t.ptrSize {
+			addr := region.addr + off
+			value, _ := mem.word(addr, t.ptrSize)
+			if entries[value] {
+				t.taken[value] = true
+			}
+			if fun := t.readItab(mem, addr, entries); fun != nil {
+				t.itabs = append(t.itabs, fun)
+			}
+		}
+	}
+	return t, nil
+}
+
+// readItab returns the fun table of the itab at addr, or nil if there is no itab.
+// An itab starts with a pointer to an interface type and a pointer to the concrete type,
+// followed by one function per method of the interface.
+func (t *Targets) readItab(mem *memory, addr uint64, entries map[uint64]bool) []uint64 {
+	inter, ok := mem.word(addr, t.ptrSize)
+	if !ok || inter == 0 {
+		return nil
+	}
+	if typ, ok := mem.word(addr+t.ptrSize, t.ptrSize); !ok || typ == 0 || !mem.contains(typ) {
+		return nil
+	}
+	kind, ok := mem.byte(inter + typeWords*t.ptrSize + typeKindBytes)
+	if !ok || kind&kindMask != kindInterface {
+		return nil
+	}
+	// InterfaceType embeds abi.Type, followed by PkgPath (a pointer) and the Methods slice.
+	methods, ok := mem.word(inter+t.typeSize()+2*t.ptrSize, t.ptrSize)
+	if !ok || methods == 0 || methods > maxMethods {
+		return nil
+	}
+	fun := make([]uint64, 0, methods)
+	for i := uint64(0); i < methods; i++ {
+		entry, ok := mem.word(addr+t.funOffset()+i*t.ptrSize, t.ptrSize)
+		if !ok || !entries[entry] {
+			return nil
+		}
+		fun = append(fun, entry)
+	}
+	return fun
+}
+
+// typeSize returns the size of abi.Type.
+func (t *Targets) typeSize() uint64 {
+	// Size_, PtrBytes, Hash, TFlag, Align_, FieldAlign_, Kind_, Equal, GCData, Str and PtrToThis.
+	return typeWords*t.ptrSize + typeKindBytes + 1 + 2*t.ptrSize + 4 + 4
+}
+
+// funOffset returns the offset of the fun table in an itab.
+func (t *Targets) funOffset() uint64 {
+	return alignUp(2*t.ptrSize+4, t.ptrSize)
+}
+
+// MethodIndex returns the index in the fun table of an itab of a function loaded at offset from
+// the start of the itab.
+func (t *Targets) MethodIndex(offset int64) (int, bool) {
+	if t == nil || offset < 0 {
+		return 0, false
+	}
+	//nolint:gosec
+	off := uint64(offset)
+	if off < t.funOffset() || (off-t.funOffset())%t.ptrSize != 0 {
+		return 0, false
+	}
+	return int((off - t.funOffset()) / t.ptrSize), true //nolint:gosec
+}
+
+// Methods returns the functions at index in the fun tables of all itabs, sorted by address.
+// When no itab has that many methods, all functions whose address is taken are returned.
+func (t *Targets) Methods(index int) []uint64 {
+	if t == nil {
+		return nil
+	}
+	methods := make(map[uint64]bool)
+	for _, fun := range t.itabs {
+		if index < len(fun) {
+			methods[fun[index]] = true
+		}
+	}
+	if len(methods) == 0 {
+		return t.FuncValues()
+	}
+	return sortedKeys(methods)
+}
+
+// FuncValues returns the functions whose address is taken, sorted by address.
+// These are the possible targets of func value calls.
+func (t *Targets) FuncValues() []uint64 {
+	if t == nil {
+		return nil
+	}
+	return sortedKeys(t.taken)
+}
+
+// Take records a function whose address is materialized by code, such as the code pointer
+// of a closure that is built at run time.
+func (t *Targets) Take(entry uint64) {
+	if t != nil {
+		t.taken[entry] = true
+	}
+}
+
+// Table returns the targets of the jump table at addr, whose entries are words of size bytes.
+// Entries are read in order and converted by target until it rejects one, the end of the table
+// is not recorded in the binary.
+func (t *Targets) Table(addr, size uint64, target func(entry uint64) (uint64, bool)) []uint64 {
+	if t == nil {
+		return nil
+	}
+	var targets []uint64
+	for i := uint64(0); i < maxEntries; i++ {
+		entry, ok := t.mem.word(addr+i*size, size)
+		if !ok {
+			break
+		}
+		if entry, ok = target(entry); !ok {
+			break
+		}
+		targets = append(targets, entry)
+	}
+	return targets
+}
+
+func sortedKeys(set map[uint64]bool) []uint64 {
+	keys := make([]uint64, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func alignUp(value, align uint64) uint64 {
+	return (value + align - 1) / align * align
+}
+
+// memory gives access to the loaded, non executable sections of a binary by address.
+type memory struct {
+	order   binary.ByteOrder
+	regions []region
+}
+
+type region struct {
+	addr uint64
+	data []byte
+}
+
+// readData reads the allocated data sections of the binary. The Go line table is left out,
+// it only holds offsets.
+func readData(file *elf.File) (*memory, error) {
+	mem := &memory{order: file.ByteOrder}
+	for _, section := range file.Sections {
+		if section.Type != elf.SHT_PROGBITS || section.Flags&elf.SHF_ALLOC == 0 ||
+			section.Flags&elf.SHF_EXECINSTR != 0 || section.Name == ".gopclntab" {
+			continue
+		}
+		data, err := section.Data()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s section: %w", section.Name, err)
+		}
+		mem.regions = append(mem.regions, region{addr: section.Addr, data: data})
+	}
+	return mem, nil
+}
+
+// bytes returns size bytes at addr, if they lie within a single section.
+func (m *memory) bytes(addr, size uint64) ([]byte, bool) {
+	for _, r := range m.regions {
+		if addr >= r.addr && size <= uint64(len(r.data)) && addr-r.addr <= uint64(len(r.data))-size {
+			return r.data[addr-r.addr : addr-r.addr+size], true
+		}
+	}
+	return nil, false
+}
+
+func (m *memory) contains(addr uint64) bool {
+	_, ok := m.bytes(addr, 1)
+	return ok
+}
+
+func (m *memory) byte(addr uint64) (byte, bool) {
+	b, ok := m.bytes(addr, 1)
+	if !ok {
+		return 0, false
+	}
+	return b[0], true
+}
+
+// word returns the pointer sized word at addr.
+func (m *memory) word(addr, size uint64) (uint64, bool) {
+	b, ok := m.bytes(addr, size)
+	if !ok {
+		return 0, false
+	}
+	if size == 4 {
+		return uint64(m.order.Uint32(b)), true
+	}
+	return m.order.Uint64(b), true
+}
@@ -0,0 +1,71 @@
+package indirect
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMethodIndex(t *testing.T) {
+	t64 := &Targets{ptrSize: 8}
+	t32 := &Targets{ptrSize: 4}
+	tests := []struct {
+		targets *Targets
+		offset  int64
+		index   int
+		ok      bool
+	}{
+		{targets: t64, offset: 24, index: 0, ok: true},
+		{targets: t64, offset: 40, index: 2, ok: true},
+		{targets: t64, offset: 16, ok: false}, // hash and padding
+		{targets: t64, offset: 28, ok: false},
+		{targets: t32, offset: 12, index: 0, ok: true},
+		{targets: t32, offset: 20, index: 2, ok: true},
+		{targets: t32, offset: 8, ok: false},
+		{targets: nil, offset: 24, ok: false},
+	}
+	for _, tt := range tests {
+		index, ok := tt.targets.MethodIndex(tt.offset)
+		assert.Equal(t, tt.ok, ok, "offset %d", tt.offset)
+		assert.Equal(t, tt.index, index, "offset %d", tt.offset)
+	}
+}
+
+func TestTargetsWithoutBinary(t *testing.T) {
+	var targets *Targets
+	targets.Take(0x11000)
+	assert.Empty(t, targets.FuncValues())
+	assert.Empty(t, targets.Methods(0))
+
+	targets = &Targets{ptrSize: 8, taken: make(map[uint64]bool)}
+	targets.Take(0x12000)
+	targets.Take(0x11000)
+	assert.Equal(t, []uint64{0x11000, 0x12000}, targets.FuncValues())
+	// Without itabs every function whose address is taken is a candidate method
+	assert.Equal(t, []uint64{0x11000, 0x12000}, targets.Methods(1))
+
+	targets.itabs = [][]uint64{{0x13000, 0x14000}, {0x15000}}
+	assert.Equal(t, []uint64{0x13000, 0x15000}, targets.Methods(0))
+	assert.Equal(t, []uint64{0x14000}, targets.Methods(1))
+}
+
+func TestTable(t *testing.T) {
+	var targets *Targets
+	assert.Empty(t, targets.Table(0x20000, 4, func(entry uint64) (uint64, bool) { return entry, true }))
+
+	data := binary.BigEndian.AppendUint32(nil, 0x11010)
+	data = binary.BigEndian.AppendUint32(data, 0x11020)
+	data = binary.BigEndian.AppendUint32(data, 0x11010)
+	data = binary.BigEndian.AppendUint32(data, 0x30000) // The next table, or other data
+	targets = &Targets{ptrSize: 4, mem: &memory{order: binary.BigEndian, regions: []region{{addr: 0x20000, data: data}}}}
+	inFunction := func(entry uint64) (uint64, bool) {
+		return entry, entry >= 0x11000 && entry < 0x11100
+	}
+	assert.Equal(t, []uint64{0x11010, 0x11020, 0x11010}, targets.Table(0x20000, 4, inFunction))
+	assert.Equal(t, []uint64{0x11020, 0x11010}, targets.Table(0x20004, 4, inFunction))
+	// The table ends with its section
+	assert.Equal(t, []uint64{0x11010, 0x11020, 0x11010, 0x30000},
+		targets.Table(0x20000, 4, func(entry uint64) (uint64, bool) { return entry, true }))
+	assert.Empty(t, targets.Table(0x10000, 4, inFunction))
+}
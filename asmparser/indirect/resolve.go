@@ -0,0 +1,246 @@
+package indirect
+
+import (
+	"bytes"
+	"debug/elf"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/ChainSafe/vm-compat/asmparser"
+)
+
+// OpKind classifies the instructions the resolution of indirect calls follows.
+type OpKind int
+
+const (
+	OpOther  OpKind = iota
+	OpLoad          // Dest = the Size bytes at Src[0] + Imm.
+	OpAdd           // Dest = Src[0] + Src[1], both registers other than zero.
+	OpShift         // Dest = Src[0] << Imm.
+	OpUpper         // Dest = Imm, the upper part of an address completed by an OpAddImm.
+	OpAddImm        // Dest = Src[0] + Imm.
+	OpJump          // Call or jump to the address in Src[0], other than a return.
+)
+
+// NoRegister is the destination of the instructions that do not write a register.
+const NoRegister = -1
+
+// Op is an instruction decoded by its architecture into the operations followed here.
+type Op struct {
+	Addr uint64
+	Kind OpKind
+	Dest int // Register written by the instruction, of any kind, or NoRegister.
+	Src  [2]int
+	Imm  int64
+	Size uint64 // Bytes read by an OpLoad.
+}
+
+// Function is a function of the binary, with its instructions in address order.
+type Function struct {
+	Entry uint64
+	Ops   []Op
+	// Values returns the constants register may hold right before the instruction at idx.
+	Values func(idx int, register int) []int64
+}
+
+// Call is a call or jump through a register, with its candidate targets.
+type Call struct {
+	Function int // Index of the function holding the call.
+	Index    int // Index of the call in the instructions of its function.
+	Kind     asmparser.IndirectKind
+	Table    []uint64 // Targets within the function of a jump through a jump table.
+	Targets  []uint64 // Entries of the functions the call may reach.
+}
+
+// Resolver links the indirect calls of the functions of a binary to their candidate targets.
+type Resolver struct {
+	Functions []*Function // Functions sorted by entry.
+	Context   int         // Register the Go toolchain passes the funcval of a closure call in.
+	// DelaySlots is set when the instruction after a jump executes before its target, as on MIPS.
+	DelaySlots bool
+}
+
+// Resolve returns the indirect calls of the functions, with the targets read from the data
+// sections of binary. Without a binary every indirect call is unresolved.
+func (r *Resolver) Resolve(binary []byte) ([]*Call, error) {
+	entries := make(map[uint64]bool, len(r.Functions))
+	for _, fn := range r.Functions {
+		entries[fn.Entry] = true
+	}
+	var targets *Targets
+	if len(binary) > 0 {
+		file, err := elf.NewFile(bytes.NewReader(binary))
+		if err != nil {
+			return nil, fmt.Errorf("error reading elf file: %w", err)
+		}
+		if targets, err = Read(file, entries); err != nil {
+			return nil, fmt.Errorf("error reading indirect call targets: %w", err)
+		}
+		for _, fn := range r.Functions {
+			takeAddresses(fn, entries, targets)
+		}
+	}
+
+	calls := make([]*Call, 0)
+	for k, fn := range r.Functions {
+		for idx, op := range fn.Ops {
+			if op.Kind != OpJump {
+				continue
+			}
+			call := &Call{Function: k, Index: idx, Kind: asmparser.IndirectUnknown}
+			if table := jumpTable(fn, idx, targets, entries); len(table) > 0 {
+				call.Kind = asmparser.IndirectJumpTable
+				for _, addr := range table {
+					if _, ok := fn.indexAt(addr); ok {
+						call.Table = append(call.Table, addr)
+					} else {
+						call.Targets = append(call.Targets, addr)
+					}
+				}
+			} else if load, ok := targetLoad(fn, idx); ok {
+				index, isMethod := targets.MethodIndex(load.Imm)
+				switch {
+				case r.isClosureCall(fn, load, idx):
+					call.Kind, call.Targets = asmparser.IndirectClosure, targets.FuncValues()
+				case isMethod:
+					call.Kind, call.Targets = asmparser.IndirectItab, targets.Methods(index)
+				}
+			}
+			calls = append(calls, call)
+		}
+	}
+	return calls, nil
+}
+
+// jumpTable returns the targets of the indirect jump at idx when it dispatches through a table
+// of code addresses: the entry is loaded at the sum of the address of the table and of an index
+// scaled to the size of the entries, and holds either the target or its offset from a base address added
+// to the entry, as in position independent code. Only targets within the function of the jump
+// and function entries are accepted, and the table ends at the first other entry.
+func jumpTable(fn *Function, idx int, targets *Targets, entries map[uint64]bool) []uint64 {
+	if targets == nil {
+		return nil
+	}
+	load, ok := fn.writerOf(idx, fn.Ops[idx].Src[0])
+	if !ok {
+		return nil
+	}
+	bases, relative := []int64{0}, false
+	if sum := fn.Ops[load]; sum.Kind == OpAdd {
+		for _, operands := range [][2]int{{sum.Src[0], sum.Src[1]}, {sum.Src[1], sum.Src[0]}} {
+			if entry, ok := fn.writerOf(load, operands[0]); ok && fn.Ops[entry].Kind == OpLoad {
+				bases, relative = fn.Values(load, operands[1]), true
+				load = entry
+				break
+			}
+		}
+	}
+	loadOp := fn.Ops[load]
+	if loadOp.Kind != OpLoad {
+		return nil
+	}
+	address, ok := fn.writerOf(load, loadOp.Src[0])
+	if !ok || fn.Ops[address].Kind != OpAdd {
+		return nil
+	}
+	// The index is scaled to the size of the entries, the other operand is the address of the table
+	var tables []int64
+	sum := fn.Ops[address]
+	for _, operands := range [][2]int{{sum.Src[0], sum.Src[1]}, {sum.Src[1], sum.Src[0]}} {
+		if index, ok := fn.writerOf(address, operands[0]); ok && fn.Ops[index].scales(loadOp.Size) {
+			tables = fn.Values(address, operands[1])
+			break
+		}
+	}
+
+	var result []uint64
+	for _, table := range tables {
+		for _, base := range bases {
+			//nolint:gosec
+			result = append(result, targets.Table(uint64(table+loadOp.Imm), loadOp.Size, func(entry uint64) (uint64, bool) {
+				if relative {
+					entry = uint64(base + int64(int32(entry)))
+				}
+				_, inFunction := fn.indexAt(entry)
+				return entry, inFunction || entries[entry]
+			})...)
+		}
+	}
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+// takeAddresses records the functions whose address is built by an OpUpper and OpAddImm pair,
+// the way the Go toolchain materializes the code pointer of a closure.
+func takeAddresses(fn *Function, entries map[uint64]bool, targets *Targets) {
+	upper := make(map[int]int64) // register -> value set by the OpUpper
+	for _, op := range fn.Ops {
+		switch op.Kind {
+		case OpUpper:
+			upper[op.Dest] = op.Imm
+		case OpAddImm:
+			hi, ok := upper[op.Src[0]]
+			if !ok {
+				continue
+			}
+			//nolint:gosec
+			if addr := uint64(hi + op.Imm); entries[addr] {
+				targets.Take(addr)
+			}
+		}
+	}
+}
+
+// targetLoad returns the load that set the target register of the indirect jump at idx,
+// if the target was loaded from memory.
+func targetLoad(fn *Function, idx int) (Op, bool) {
+	writer, ok := fn.writerOf(idx, fn.Ops[idx].Src[0])
+	if !ok || fn.Ops[writer].Kind != OpLoad {
+		return Op{}, false
+	}
+	return fn.Ops[writer], true
+}
+
+// isClosureCall reports whether the indirect jump at idx, whose target was set by load, calls a
+// func value. Go passes the funcval of a closure call in the context register, and the target is
+// loaded from the first word of the funcval.
+func (r *Resolver) isClosureCall(fn *Function, load Op, idx int) bool {
+	if load.Src[0] == r.Context && load.Imm == 0 {
+		return true
+	}
+	// The delay slot executes before the callee
+	if r.DelaySlots && idx+1 < len(fn.Ops) && fn.Ops[idx+1].Dest == r.Context {
+		return true
+	}
+	for i := idx - 1; i >= 0 && fn.Ops[i].Addr != load.Addr; i-- {
+		if fn.Ops[i].Dest == r.Context {
+			return true
+		}
+	}
+	return false
+}
+
+// writerOf returns the index of the last instruction before idx in the function that writes register.
+func (fn *Function) writerOf(idx int, register int) (int, bool) {
+	for i := idx - 1; i >= 0; i-- {
+		if fn.Ops[i].Dest == register {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// indexAt returns the index of the instruction at addr, if the function holds it.
+func (fn *Function) indexAt(addr uint64) (int, bool) {
+	idx := sort.Search(len(fn.Ops), func(i int) bool {
+		return fn.Ops[i].Addr >= addr
+	})
+	return idx, idx < len(fn.Ops) && fn.Ops[idx].Addr == addr
+}
+
+// scales checks if the instruction is a shift by the logarithm of size, scaling an index
+// to entries of size bytes.
+func (op Op) scales(size uint64) bool {
+	return op.Kind == OpShift && op.Imm >= 0 && op.Imm < 64 && uint64(1)<<op.Imm == size
+}
@@ -0,0 +1,240 @@
+package mips
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/ChainSafe/vm-compat/asmparser"
+)
+
+// block represents a basic block of a segment implementing the asmparser.BasicBlock interface.
+type block struct {
+	seg          *segment
+	start, end   int // Range of the block in the instructions of the segment.
+	successors   []*block
+	predecessors []*block
+}
+
+func (b *block) Address() string {
+	return b.seg.instructions[b.start].Address()
+}
+
+func (b *block) Instructions() []asmparser.Instruction {
+	instrs := make([]asmparser.Instruction, 0, b.end-b.start)
+	for _, ins := range b.seg.instructions[b.start:b.end] {
+		instrs = append(instrs, ins)
+	}
+	return instrs
+}
+
+func (b *block) Successors() []asmparser.BasicBlock {
+	return basicBlocks(b.successors)
+}
+
+func (b *block) Predecessors() []asmparser.BasicBlock {
+	return basicBlocks(b.predecessors)
+}
+
+func basicBlocks(blocks []*block) []asmparser.BasicBlock {
+	result := make([]asmparser.BasicBlock, len(blocks))
+	for i, b := range blocks {
+		result[i] = b
+	}
+	return result
+}
+
+// branch describes how a jump or branch instruction transfers control within its function.
+type branch struct {
+	target    uint64 // Destination, valid when hasTarget is set.
+	hasTarget bool
+	next      bool // Control may continue after the delay slot when the branch is not taken.
+	likely    bool // The delay slot only executes when the branch is taken.
+}
+
+// branchOf returns the control transfer of the instruction, or false if control always continues
+// with the next instruction. Calls return after their delay slot and are not transfers.
+func (i *instruction) branchOf() (branch, bool) {
+	switch i.opcode {
+	case 0x02: // j
+		//nolint:gosec
+		return branch{target: uint64(i.jumpTarget()), hasTarget: true}, true
+	case 0x04: // beq, b when both registers are $zero
+		unconditional := i.operands[0] == registerZero && i.operands[1] == registerZero
+		return branch{target: i.branchTarget(), hasTarget: true, next: !unconditional}, true
+	case 0x05, 0x06, 0x07: // bne, blez, bgtz
+		return branch{target: i.branchTarget(), hasTarget: true, next: true}, true
+	case 0x14, 0x15, 0x16, 0x17: // beql, bnel, blezl, bgtzl
+		return branch{target: i.branchTarget(), hasTarget: true, next: true, likely: true}, true
+	case 0x01: // REGIMM: bltz, bgez, bltzl and bgezl, the linking variants are calls
+		if rt := i.fields[fieldRT]; rt <= 0x03 {
+			return branch{target: i.branchTarget(), hasTarget: true, next: true, likely: rt >= 0x02}, true
+		}
+	case 0x11: // COP1: bc1f and bc1t, bc1fl and bc1tl when the nd bit of rt is set
+		if i.fields[fieldFmt] == 0x08 {
+			return branch{target: i.branchTarget(), hasTarget: true, next: true, likely: i.operands[1]&0x2 != 0}, true
+		}
+	case 0x00:
+		if i.operands[4] == 0x08 { // jr: a return, or a jump through a register
+			return branch{}, true
+		}
+	}
+	return branch{}, false
+}
+
+// branchTarget returns the destination of a branch, relative to its delay slot.
+func (i *instruction) branchTarget() uint64 {
+	//nolint:gosec
+	return i.address + 4 + uint64(i.operands[2]<<2)
+}
+
+// buildBlocks splits the instructions of the segment into basic blocks. A block ends after
+// the delay slot of a jump or branch, and a new one starts at every target of a branch
+// or jump table.
+// The delay slot of a branch likely, which is annulled when the branch is not taken,
+// is a block of its own that only continues at the target.
+func (s *segment) buildBlocks() {
+	s.blocks = make([]*block, 0)
+	if len(s.instructions) == 0 {
+		return
+	}
+	leaders := map[int]bool{0: true}
+	for idx, instr := range s.instructions {
+		br, ok := instr.branchOf()
+		if !ok {
+			continue
+		}
+		leaders[idx+2] = true
+		if br.likely {
+			leaders[idx+1] = true
+		}
+		if target, ok := s.indexAt(br.target); ok && br.hasTarget {
+			leaders[target] = true
+		}
+		for _, addr := range s.tables[idx] {
+			if target, ok := s.indexAt(addr); ok {
+				leaders[target] = true
+			}
+		}
+	}
+	starts := make([]int, 0, len(leaders))
+	for start := range leaders {
+		if start < len(s.instructions) {
+			starts = append(starts, start)
+		}
+	}
+	sort.Ints(starts)
+	for k, start := range starts {
+		end := len(s.instructions)
+		if k+1 < len(starts) {
+			end = starts[k+1]
+		}
+		s.blocks = append(s.blocks, &block{seg: s, start: start, end: end})
+	}
+	for k, b := range s.blocks {
+		for _, succ := range s.successorsOf(k) {
+			b.successors = append(b.successors, succ)
+			succ.predecessors = append(succ.predecessors, b)
+		}
+	}
+	s.orderExecution()
+}
+
+// orderExecution computes the order the instructions of each block execute in. The delay slot
+// of a jump, branch or call executes before control reaches the target, so the transfer is
+// ordered after its delay slot: a call clobbers the registers set in its delay slot, and the
+// delay slot of a call executes before the callee.
+func (s *segment) orderExecution() {
+	s.order = make([]int, len(s.instructions))
+	s.position = make([]int, len(s.instructions))
+	for idx := range s.instructions {
+		s.order[idx] = idx
+	}
+	for _, b := range s.blocks {
+		for idx := b.start; idx+1 < b.end; idx++ {
+			instr := s.instructions[idx]
+			if _, ok := instr.branchOf(); ok || instr.isCall() {
+				s.order[idx], s.order[idx+1] = idx+1, idx
+				idx++ // A delay slot holds no transfer
+			}
+		}
+	}
+	for pos, idx := range s.order {
+		s.position[idx] = pos
+	}
+}
+
+// successorsOf returns the blocks control may continue with after the k-th block.
+func (s *segment) successorsOf(k int) []*block {
+	b := s.blocks[k]
+	var next *block
+	if k+1 < len(s.blocks) {
+		next = s.blocks[k+1]
+	}
+	if br, ok := s.instructions[b.end-1].branchOf(); ok {
+		if br.likely && k+2 < len(s.blocks) {
+			// The annulled delay slot is skipped when the branch is not taken
+			return []*block{next, s.blocks[k+2]}
+		}
+		// The delay slot starts the next block, as it is also the target of a branch
+		return nonNil(next)
+	}
+	if b.end < 2 {
+		return nonNil(next)
+	}
+	br, ok := s.instructions[b.end-2].branchOf()
+	if !ok {
+		return nonNil(next)
+	}
+	successors := make([]*block, 0, 2)
+	if target, ok := s.indexAt(br.target); ok && br.hasTarget {
+		successors = append(successors, s.blockOf(target))
+	}
+	for _, addr := range s.tables[b.end-2] {
+		if target, ok := s.indexAt(addr); ok && !slices.Contains(successors, s.blockOf(target)) {
+			successors = append(successors, s.blockOf(target))
+		}
+	}
+	// A delay slot entered from another block continues with the next one, unless it is
+	// the annulled delay slot of a branch likely
+	if !br.likely && (br.next || b.end-2 < b.start) && next != nil && !slices.Contains(successors, next) {
+		successors = append(successors, next)
+	}
+	return successors
+}
+
+// blockOf returns the block holding the instruction at idx.
+func (s *segment) blockOf(idx int) *block {
+	k := sort.Search(len(s.blocks), func(k int) bool {
+		return s.blocks[k].end > idx
+	})
+	return s.blocks[k]
+}
+
+// indexAt returns the index of the instruction at addr, if the segment holds it.
+func (s *segment) indexAt(addr uint64) (int, bool) {
+	idx := sort.Search(len(s.instructions), func(i int) bool {
+		return s.instructions[i].address >= addr
+	})
+	return idx, idx < len(s.instructions) && s.instructions[idx].address == addr
+}
+
+// predecessors returns the indexes of the instructions that may execute right before the
+// instruction at idx, and whether the instruction may be reached from the entry of the segment.
+func (s *segment) predecessors(idx int) ([]int, bool) {
+	b := s.blockOf(idx)
+	if pos := s.position[idx]; pos > b.start {
+		return []int{s.order[pos-1]}, false
+	}
+	preds := make([]int, 0, len(b.predecessors))
+	for _, pred := range b.predecessors {
+		preds = append(preds, s.order[pred.end-1])
+	}
+	return preds, b.start == 0
+}
+
+func nonNil(b *block) []*block {
+	if b == nil {
+		return nil
+	}
+	return []*block{b}
+}
@@ -0,0 +1,54 @@
+package mips
+
+import (
+	"debug/elf"
+	"fmt"
+	"io"
+
+	"github.com/ChainSafe/vm-compat/asmparser/elftext"
+)
+
+// parseELF decodes the .text section of a MIPS ELF binary into a CallGraph.
+// Segments are derived from the function symbols of the symbol table, the
+// same way llvm-objdump labels its output.
+func (p *parserImpl) parseELF(r io.ReaderAt) (*callGraph, error) {
+	file, err := elf.NewFile(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading elf file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	if file.Machine != elf.EM_MIPS {
+		return nil, fmt.Errorf("unsupported elf machine: %s", file.Machine)
+	}
+	text, err := elftext.Read(file)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := newCallGraph()
+	lineNum := 0
+	for i, sym := range text.Functions {
+		end := text.End(i)
+		lineNum++
+		currSegment := newSegment(sym.Value, sym.Name)
+		graph.addSegment(currSegment)
+		for pc := sym.Value; pc+4 <= end; pc += 4 {
+			offset := pc - text.Addr
+			word := file.ByteOrder.Uint32(text.Code[offset : offset+4])
+			instr := decodeWord(word)
+			instr.address = pc
+			instr.opcodeString = mnemonicOf(word)
+			lineNum++
+			instr.line = lineNum
+			currSegment.instructions = append(currSegment.instructions, instr)
+			if instr.isJump() {
+				//nolint
+				graph.addParent(uint64(instr.jumpTarget()), currSegment.address)
+			}
+		}
+	}
+	return graph, nil
+}
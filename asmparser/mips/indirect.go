@@ -0,0 +1,164 @@
+package mips
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/ChainSafe/vm-compat/asmparser"
+	"github.com/ChainSafe/vm-compat/asmparser/indirect"
+)
+
+// resolveIndirect links the calls and jumps through a register to their candidate targets,
+// read from the data sections of binary. Without a binary every indirect call is unresolved.
+// The targets of a jump table within its function are successors of the block of the jump,
+// so the blocks of those functions are built again.
+func (g *callGraph) resolveIndirect(binary []byte) error {
+	segments := g.functions()
+	resolver := &indirect.Resolver{Functions: make([]*indirect.Function, 0, len(segments)), Context: registerCtxt, DelaySlots: true}
+	for _, seg := range segments {
+		resolver.Functions = append(resolver.Functions, g.function(seg))
+	}
+	calls, err := resolver.Resolve(binary)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range calls {
+		seg := segments[c.Function]
+		instr := seg.instructions[c.Index]
+		call := &asmparser.IndirectCall{Segment: seg, Instruction: instr, Kind: c.Kind}
+		if len(c.Table) > 0 {
+			seg.tables[c.Index] = c.Table
+		}
+		resolved := make(map[uint64]bool, len(c.Targets))
+		for _, addr := range c.Targets {
+			if target, ok := g.segments[addr]; ok {
+				resolved[addr] = true
+				call.Targets = append(call.Targets, target)
+				g.addParent(addr, seg.address)
+			}
+		}
+		g.indirectTargets[instr] = resolved
+		g.indirect = append(g.indirect, call)
+	}
+
+	for _, seg := range segments {
+		if len(seg.tables) > 0 {
+			seg.buildBlocks()
+		}
+	}
+	for _, call := range g.indirect {
+		if call.Kind != asmparser.IndirectJumpTable {
+			continue
+		}
+		seg := call.Segment.(*segment)                                                  //nolint:forcetypeassert
+		for _, addr := range seg.tables[seg.indexOf(call.Instruction.(*instruction))] { //nolint:forcetypeassert
+			idx, _ := seg.indexAt(addr)
+			if b := seg.blockOf(idx); !slices.Contains(call.Blocks, asmparser.BasicBlock(b)) {
+				call.Blocks = append(call.Blocks, b)
+			}
+		}
+	}
+	return nil
+}
+
+// function returns the instructions of the segment in the form the resolution of indirect calls follows.
+func (g *callGraph) function(seg *segment) *indirect.Function {
+	fn := &indirect.Function{
+		Entry: seg.address,
+		Ops:   make([]indirect.Op, 0, len(seg.instructions)),
+		Values: func(idx int, register int) []int64 {
+			return g.valuesOf(seg, idx, int64(register))
+		},
+	}
+	for _, instr := range seg.instructions {
+		fn.Ops = append(fn.Ops, instr.indirectOp())
+	}
+	return fn
+}
+
+// functions returns the segments holding instructions, sorted by address.
+func (g *callGraph) functions() []*segment {
+	segments := make([]*segment, 0, len(g.segments))
+	for _, seg := range g.segments {
+		if len(seg.instructions) > 0 {
+			segments = append(segments, seg)
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].address < segments[j].address
+	})
+	return segments
+}
+
+// indirectOp decodes the instruction into the operations followed by the resolution of indirect calls.
+//
+//nolint:gosec
+func (i *instruction) indirectOp() indirect.Op {
+	op := indirect.Op{Addr: i.address, Kind: indirect.OpOther, Dest: indirect.NoRegister}
+	if dest, ok := i.destination(); ok {
+		op.Dest = int(dest)
+	}
+	switch {
+	case i.isIndirectJump():
+		op.Kind, op.Src[0] = indirect.OpJump, int(i.operands[0])
+	case i.isLoad():
+		op.Kind, op.Src[0], op.Imm, op.Size = indirect.OpLoad, int(i.operands[0]), i.operands[2], 4
+		if i.opcode == 0x37 { // ld
+			op.Size = 8
+		}
+	case i.isRegisterAdd():
+		op.Kind, op.Src = indirect.OpAdd, [2]int{int(i.operands[0]), int(i.operands[1])}
+	case i.opcode == 0x00 && (i.operands[4] == 0x00 || i.operands[4] == 0x38): // sll, dsll
+		op.Kind, op.Src[0], op.Imm = indirect.OpShift, int(i.operands[1]), i.operands[3]
+	case i.opcode == 0x0f: // lui
+		op.Kind, op.Imm = indirect.OpUpper, int64(int32(i.operands[2]<<16))
+	case i.opcode == 0x09 || i.opcode == 0x19: // addiu, daddiu
+		op.Kind, op.Src[0], op.Imm = indirect.OpAddImm, int(i.operands[0]), i.operands[2]
+	case i.opcode == 0x0d: // ori, which adds the low bits of an address to those set by lui
+		op.Kind, op.Src[0], op.Imm = indirect.OpAddImm, int(i.operands[0]), i.operands[2]&0xffff
+	}
+	return op
+}
+
+// isIndirectJump checks if the instruction is a jalr, or a jr other than a return through $ra.
+func (i *instruction) isIndirectJump() bool {
+	if i.opcode != 0x00 || len(i.operands) < 5 {
+		return false
+	}
+	switch i.operands[4] {
+	case 0x09: // jalr
+		return true
+	case 0x08: // jr
+		return i.operands[0] != registerRA
+	}
+	return false
+}
+
+// isRegisterAdd checks if the instruction is add, addu, dadd or daddu of two registers other than $zero.
+func (i *instruction) isRegisterAdd() bool {
+	if i.opcode != 0x00 || i.operands[0] == registerZero || i.operands[1] == registerZero {
+		return false
+	}
+	switch i.operands[4] {
+	case 0x20, 0x21, 0x2c, 0x2d:
+		return true
+	}
+	return false
+}
+
+// destination returns the register written by the instruction, if any.
+func (i *instruction) destination() (int64, bool) {
+	switch i.opcode {
+	case 0x00, 0x1c: // SPECIAL and SPECIAL2 write rd, zero when the result goes to hi and lo
+		return i.operands[2], i.operands[2] != registerZero
+	case 0x03: // jal
+		return registerRA, true
+	case 0x02, 0x01, 0x04, 0x05, 0x06, 0x07, 0x14, 0x15, 0x16, 0x17, // jumps and branches
+		0x28, 0x29, 0x2a, 0x2b, 0x2c, 0x2d, 0x2e, 0x2f, 0x33, 0x39, 0x3d, 0x3f: // stores, cache and pref
+		return 0, false
+	case 0x11, 0x1f: // COP1 and SPECIAL3 are not traced
+		return 0, false
+	}
+	return i.operands[1], true
+}
@@ -3,21 +3,27 @@ package mips
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/ChainSafe/vm-compat/asmparser"
+	"github.com/ChainSafe/vm-compat/asmparser/elftext"
 )
 
 // Constants defining MIPS register indexes.
 const (
 	registerZero = 0  // $zero register index in MIPS
 	registerV0   = 2  // $v0 register index in MIPS
+	registerCtxt = 22 // Closure context register (REGCTXT) of the Go toolchain
 	registerSP   = 29 // $sp (Stack Pointer)
+	registerRA   = 31 // $ra (Return Address)
 )
 
 var (
@@ -28,31 +34,51 @@ var (
 )
 
 // parserImpl implements the asmparser.Parser interface.
-type parserImpl struct{}
+type parserImpl struct {
+	order  binary.ByteOrder // Byte order of the instruction bytes printed by llvm-objdump.
+	config *asmparser.Config
+}
 
-// NewParser returns a new instance of a MIPS assembly parser.
-func NewParser() asmparser.Parser {
-	return &parserImpl{}
+// NewParser returns a new instance of a MIPS assembly parser for the given byte order,
+// binary.BigEndian for mips and mips64 or binary.LittleEndian for mipsle and mips64le.
+// ELF binaries are always decoded with the byte order of their header.
+func NewParser(order binary.ByteOrder, opts ...asmparser.Option) asmparser.Parser {
+	return &parserImpl{order: order, config: asmparser.NewConfig(opts...)}
 }
 
-// Parse reads and parses a MIPS assembly file into a CallGraph.
-func (p *parserImpl) Parse(path string) (asmparser.CallGraph, error) {
-	fpath, err := filepath.Abs(path)
-	if err != nil {
-		return nil, fmt.Errorf("error resolving absolute filepath: %w", err)
+// Parse reads and parses MIPS assembly into a CallGraph.
+// The input may be llvm-objdump output or an ELF binary, which is decoded directly.
+// The format is detected from the content.
+// Indirect calls are resolved from the ELF input, or the binary set with asmparser.WithBinary.
+func (p *parserImpl) Parse(r io.Reader) (asmparser.CallGraph, error) {
+	reader := bufio.NewReader(r)
+	binary := p.config.Binary
+	var graph *callGraph
+	var err error
+	switch {
+	case elftext.IsELF(reader):
+		if binary, err = io.ReadAll(reader); err != nil {
+			return nil, fmt.Errorf("error reading elf file: %w", err)
+		}
+		graph, err = p.parseELF(bytes.NewReader(binary))
+	default:
+		graph, err = p.parseObjdump(reader)
 	}
-
-	codefile, err := os.Open(fpath)
 	if err != nil {
-		return nil, fmt.Errorf("error opening file: %w", err)
+		return nil, err
+	}
+	graph.buildBlocks()
+	if err = graph.resolveIndirect(binary); err != nil {
+		return nil, err
 	}
-	defer func() {
-		_ = codefile.Close()
-	}()
+	return graph, nil
+}
 
+// parseObjdump parses the output of llvm-objdump into a CallGraph.
+func (p *parserImpl) parseObjdump(r io.Reader) (*callGraph, error) {
 	var currSegment *segment
 	graph := newCallGraph()
-	scanner := bufio.NewScanner(codefile)
+	scanner := bufio.NewScanner(r)
 	lineNum := 0
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -72,6 +98,7 @@ func (p *parserImpl) Parse(path string) (asmparser.CallGraph, error) {
 			if currSegment == nil {
 				return nil, fmt.Errorf("invalid assembly: instruction encountered before segment definition")
 			}
+			currSegment.instructions = append(currSegment.instructions, zeroWords(currSegment, instr)...)
 			currSegment.instructions = append(currSegment.instructions, instr)
 			if instr.isJump() {
 				//nolint
@@ -82,6 +109,24 @@ func (p *parserImpl) Parse(path string) (asmparser.CallGraph, error) {
 	return graph, nil
 }
 
+// zeroWords returns the words between the last instruction of the segment and instr.
+// llvm-objdump prints a run of zero words as a single "..." line, but they are nops that
+// may be the delay slot of a branch, so they are restored at the line of the gap.
+func zeroWords(seg *segment, instr *instruction) []*instruction {
+	if len(seg.instructions) == 0 {
+		return nil
+	}
+	words := make([]*instruction, 0)
+	for addr := seg.instructions[len(seg.instructions)-1].address + 4; addr < instr.address; addr += 4 {
+		word := decodeWord(0)
+		word.opcodeString = mnemonicOf(0)
+		word.address = addr
+		word.line = instr.line - 1
+		words = append(words, word)
+	}
+	return words
+}
+
 // parseLine attempts to parse a line of MIPS assembly.
 func (p *parserImpl) parseLine(line string) (*instruction, error) {
 	line = strings.TrimSpace(line)
@@ -89,7 +134,7 @@ func (p *parserImpl) parseLine(line string) (*instruction, error) {
 	case blockStartRegex.MatchString(line):
 		return parseSegmentStart(line)
 	case instructionRegex.MatchString(line):
-		return parseInstruction(line)
+		return parseInstruction(line, p.order)
 	default:
 		return nil, nil // Ignore comments and unrecognized lines
 	}
@@ -109,12 +154,13 @@ func parseSegmentStart(line string) (*instruction, error) {
 }
 
 // parseInstruction extracts instruction information from a line.
-func parseInstruction(line string) (*instruction, error) {
+// The instruction bytes are printed in memory order and decoded with the given byte order.
+func parseInstruction(line string, order binary.ByteOrder) (*instruction, error) {
 	matches := instructionRegex.FindStringSubmatch(line)
 	if len(matches) <= 4 {
 		return nil, fmt.Errorf("failed to parse instruction: %s", line)
 	}
-	instr, err := decodeInstruction(strings.ReplaceAll(matches[3], " ", ""))
+	instr, err := decodeInstruction(strings.ReplaceAll(matches[3], " ", ""), order)
 	if err != nil {
 		return nil, fmt.Errorf("invalid MIPS instruction format: %w", err)
 	}
@@ -129,17 +175,25 @@ func parseInstruction(line string) (*instruction, error) {
 
 // decodeInstruction decodes a hexadecimal MIPS instruction.
 // https://en.wikibooks.org/wiki/MIPS_Assembly/Instruction_Formats#FI_Instructions
-func decodeInstruction(str string) (*instruction, error) {
-	_instruction, err := strconv.ParseUint(str, 16, 32)
+func decodeInstruction(str string, order binary.ByteOrder) (*instruction, error) {
+	_instruction, err := hex.DecodeString(str)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse hex instruction: %w", err)
 	}
-	instr := uint32(_instruction)
+	if len(_instruction) != 4 {
+		return nil, fmt.Errorf("invalid hex instruction length: %s", str)
+	}
+	return decodeWord(order.Uint32(_instruction)), nil
+}
+
+// decodeWord decodes a raw 32-bit MIPS instruction word.
+func decodeWord(instr uint32) *instruction {
 	opcode := (instr >> 26) & 0x3F
 
 	decodedInstruction := &instruction{
-		opcode:   opcode,
-		operands: make([]int64, 0),
+		opcode:       opcode,
+		baseMnemonic: baseMnemonicOf(instr),
+		operands:     make([]int64, 0),
 	}
 
 	switch opcode {
@@ -175,18 +229,58 @@ func decodeInstruction(str string) (*instruction, error) {
 		decodedInstruction.instType = asmparser.IType
 		//nolint
 		decodedInstruction.operands = append(decodedInstruction.operands, int64(rs), int64(rt), int64(immediate))
+		decodedInstruction.fields = decodeSubFields(opcode, instr)
+	}
+	return decodedInstruction
+}
+
+// Sub-field names of the instructions whose operation is not fully selected by opcode and funct.
+const (
+	fieldRT    = "rt"
+	fieldFmt   = "fmt"
+	fieldFunct = "funct"
+	fieldSA    = "sa"
+)
+
+// decodeSubFields returns the fields that select the operation of REGIMM, COP1 and SPECIAL3 instructions.
+func decodeSubFields(opcode, instr uint32) map[string]uint32 {
+	rs := (instr >> 21) & 0x1F
+	rt := (instr >> 16) & 0x1F
+	sa := (instr >> 6) & 0x1F
+	funct := instr & 0x3F
+
+	switch opcode {
+	case 0x01: // REGIMM: bltz, bgez, bltzal, bgezal (bal), ...
+		return map[string]uint32{fieldRT: rt}
+	case 0x11: // COP1: fmt is held in the rs field
+		switch {
+		case rs == 0x08: // bc1f, bc1t, selected by the tf bit of rt
+			return map[string]uint32{fieldFmt: rs, fieldRT: rt & 0x1}
+		case rs >= 0x10: // arithmetic on S, D, W, L or PS operands
+			return map[string]uint32{fieldFmt: rs, fieldFunct: funct}
+		default: // moves to and from the coprocessor: mfc1, mtc1, cfc1, ...
+			return map[string]uint32{fieldFmt: rs}
+		}
+	case 0x1f: // SPECIAL3: ext, ins, ...; bshfl and dbshfl select seb, seh, wsbh, ... by sa
+		if funct == 0x20 || funct == 0x24 {
+			return map[string]uint32{fieldFunct: funct, fieldSA: sa}
+		}
+		return map[string]uint32{fieldFunct: funct}
+	default:
+		return nil
 	}
-	return decodedInstruction, nil
 }
 
 // instruction represents a MIPS instruction implementing the asmparser.Instruction interface.
 type instruction struct {
 	instType     asmparser.InstructionType
 	opcodeString string
+	baseMnemonic string
 	address      uint64
 	label        string // Used if this instruction marks the start of a segment
 	opcode       uint32
-	operands     []int64 // RS, RT, RD, Shamt, FunctionCode, Immediate, TargetAddress
+	operands     []int64           // RS, RT, RD, Shamt, FunctionCode, Immediate, TargetAddress
+	fields       map[string]uint32 // Sub-fields selecting the operation of REGIMM, COP1 and SPECIAL3
 	line         int
 }
 
@@ -207,13 +301,34 @@ func (i *instruction) Funct() string {
 	if i.instType == asmparser.RType && len(i.operands) > 4 {
 		return fmt.Sprintf("0x%x", i.operands[4])
 	}
+	if funct, ok := i.fields[fieldFunct]; ok {
+		return fmt.Sprintf("0x%x", funct)
+	}
 	return ""
 }
 
+func (i *instruction) Fields() map[string]string {
+	var fields map[string]string
+	for name, value := range i.fields {
+		if name == fieldFunct {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]string, len(i.fields))
+		}
+		fields[name] = fmt.Sprintf("0x%x", value)
+	}
+	return fields
+}
+
 func (i *instruction) Mnemonic() string {
 	return i.opcodeString
 }
 
+func (i *instruction) BaseMnemonic() string {
+	return i.baseMnemonic
+}
+
 func (i *instruction) Address() string {
 	return fmt.Sprintf("0x%x", i.address)
 }
@@ -236,12 +351,22 @@ func (i *instruction) Line() int {
 	return i.line
 }
 
+// Source returns nil, the disassemblers of MIPS binaries do not report Go source positions.
+// Call stacks take them from the line tables of the binary instead.
+func (i *instruction) Source() *asmparser.SourcePosition {
+	return nil
+}
+
 // segment represents a block of assembly instructions implementing the asmparser.Segment interface.
 type segment struct {
 	address      uint64
 	label        string
 	instructions []*instruction
-	parents      map[uint64]bool // Map of parent segment addresses to prevent duplicates.
+	blocks       []*block
+	order        []int            // Indexes of the instructions in execution order, see orderExecution.
+	position     []int            // Position of every instruction in order.
+	tables       map[int][]uint64 // Targets within the segment of the jumps through a jump table, by index.
+	parents      map[uint64]bool  // Map of parent segment addresses to prevent duplicates.
 }
 
 // newSegment initializes a new segment with the given address and label.
@@ -250,6 +375,7 @@ func newSegment(address uint64, label string) *segment {
 		address:      address,
 		label:        label,
 		instructions: make([]*instruction, 0),
+		tables:       make(map[int][]uint64),
 		parents:      make(map[uint64]bool),
 	}
 }
@@ -270,20 +396,39 @@ func (s *segment) Instructions() []asmparser.Instruction {
 	return instrs
 }
 
+func (s *segment) Blocks() []asmparser.BasicBlock {
+	return basicBlocks(s.blocks)
+}
+
+// indexOf returns the position of instr in the segment, or -1.
+func (s *segment) indexOf(instr *instruction) int {
+	for i, ins := range s.instructions {
+		if ins == instr {
+			return i
+		}
+	}
+	return -1
+}
+
 // callGraph represents a graph structure implementing asmparser.CallGraph.
 type callGraph struct {
-	segments map[uint64]*segment
+	segments        map[uint64]*segment
+	indirect        []*asmparser.IndirectCall
+	indirectTargets map[*instruction]map[uint64]bool // Resolved targets of the indirect calls.
 }
 
 // newCallGraph initializes an empty call graph.
 func newCallGraph() *callGraph {
-	return &callGraph{segments: make(map[uint64]*segment)}
+	return &callGraph{
+		segments:        make(map[uint64]*segment),
+		indirectTargets: make(map[*instruction]map[uint64]bool),
+	}
 }
 
 func (g *callGraph) Segments() []asmparser.Segment {
 	segments := make([]asmparser.Segment, 0, len(g.segments))
-	for _, seg := range g.segments {
-		segments = append(segments, seg)
+	for _, addr := range sortedAddresses(g.segments) {
+		segments = append(segments, g.segments[addr])
 	}
 	return segments
 }
@@ -291,7 +436,7 @@ func (g *callGraph) Segments() []asmparser.Segment {
 func (g *callGraph) ParentsOf(seg asmparser.Segment) []asmparser.Segment {
 	if segObj, ok := seg.(*segment); ok {
 		parents := make([]asmparser.Segment, 0, len(segObj.parents))
-		for addr := range segObj.parents {
+		for _, addr := range sortedAddresses(segObj.parents) {
 			parents = append(parents, g.segments[addr])
 		}
 		return parents
@@ -299,154 +444,70 @@ func (g *callGraph) ParentsOf(seg asmparser.Segment) []asmparser.Segment {
 	return nil
 }
 
-func (g *callGraph) addParent(segmentAddr uint64, parentAddr uint64) {
-	seg, exists := g.segments[segmentAddr]
-	if !exists {
-		seg = &segment{address: segmentAddr, instructions: make([]*instruction, 0), parents: make(map[uint64]bool)}
-	}
-	seg.parents[parentAddr] = true
-	g.segments[segmentAddr] = seg
-}
-
-func (g *callGraph) addSegment(seg *segment) {
-	if existingSeg, exists := g.segments[seg.address]; exists {
-		seg.parents = existingSeg.parents
+// sortedAddresses returns the addresses keying m in increasing order, so that the segments
+// are listed in the same order on every run.
+func sortedAddresses[V any](m map[uint64]V) []uint64 {
+	addrs := make([]uint64, 0, len(m))
+	for addr := range m {
+		addrs = append(addrs, addr)
 	}
-	g.segments[seg.address] = seg
+	slices.Sort(addrs)
+	return addrs
 }
 
-// RetrieveSyscallNum extracts the syscall number by analyzing the preceding instructions.
-// Limitation: If syscall number is dynamically generated, it cannot trace that
-func (g *callGraph) RetrieveSyscallNum(seg asmparser.Segment, instr asmparser.Instruction) ([]*asmparser.Syscall, error) {
-	ins, ok := instr.(*instruction)
+func (g *callGraph) CallSites(caller, callee asmparser.Segment) []asmparser.Instruction {
+	callerObj, ok := caller.(*segment)
 	if !ok {
-		return nil, fmt.Errorf("invalid instruction type: expected MIPS instruction, got %T", instr)
+		return nil
 	}
-	s, ok := seg.(*segment)
+	calleeObj, ok := callee.(*segment)
 	if !ok {
-		return nil, fmt.Errorf("invalid segment type: expected MIPS segment, got %T", seg)
+		return nil
 	}
-	var indexOfInstr int
-	for i, _instr := range s.instructions {
-		if _instr.Address() == ins.Address() {
-			indexOfInstr = i
+	sites := make([]asmparser.Instruction, 0)
+	for _, instr := range callerObj.instructions {
+		if g.isCallTo(instr, calleeObj) {
+			sites = append(sites, instr)
 		}
 	}
-	var resolveRegisterValue func(register, offset int64, instrIdx int, seg, childSeg *segment) ([]*asmparser.Syscall, error)
-	seen := make(map[*segment]bool)
-	resolveRegisterValue = func(register, offset int64, instrIdx int, seg, childSeg *segment) ([]*asmparser.Syscall, error) {
-		result := make([]*asmparser.Syscall, 0)
-		// Special case, where we don't know from where to start
-		// Need to find out instruction index
-		if instrIdx == -2 {
-			if seen[seg] {
-				return result, nil
-			}
-			// multiple jump possible
-			for i, inst := range seg.instructions {
-				if inst.isJump() && uint64(inst.jumpTarget()) == childSeg.address { //nolint:gosec
-					res, err := resolveRegisterValue(register, offset, i, seg, childSeg)
-					if err != nil {
-						return nil, err
-					}
-					result = append(result, res...)
-				}
-			}
-			return result, nil
-		}
-		// When all the instruction has finished while processing,
-		// Need to track back to it's caller
-		if instrIdx == -1 {
-			seen[seg] = true
-			parents := g.ParentsOf(seg)
-			if len(parents) == 0 {
-				// Here, we cannot resolve any value for syscall, reasons can be it's being assigned in runtime.
-				// Fine to ignore those syscall
-				return result, nil
-			}
-			for _, sg := range parents {
-				res, err := resolveRegisterValue(register, offset, -2, sg.(*segment), seg)
-				if err != nil {
-					return nil, err
-				}
-				result = append(result, res...)
-			}
-			return result, nil
-		}
+	return sites
+}
 
-		currInstr := seg.instructions[instrIdx]
-		switch currInstr.instType {
-		case asmparser.RType:
-			if len(currInstr.operands) > 2 {
-				rd := currInstr.operands[2] // destination register
-				// If the destination register is our target register,
-				// we need to resolve the value for it
-				if rd == register {
-					return nil, fmt.Errorf("not handled modification of register in r-type instruction, instruction:%s", currInstr.Address())
-				}
-			}
-		case asmparser.IType:
-			if len(currInstr.operands) > 1 {
-				rs := currInstr.operands[0]
-				rt := currInstr.operands[1]
-				if rs == register || rt == register {
-					switch currInstr.opcode {
-					case 0x23, 0x24, 0x27, 0x37: // load from rs to rt - rt matters
-						if register == rt {
-							// Load to sp - need to match offset
-							if rt == registerSP && offset == currInstr.operands[2] {
-								register = rs
-							}
-							// Load from SP - update offset
-							if rs == registerSP {
-								offset = currInstr.operands[2]
-								register = rs
-							}
-						}
-					case 0x2B, 0x3F, 0x28: // store to rs from rt - rs matters
-						if register == rs {
-							// Store to SP - need to  match the offset
-							if rs == registerSP && offset == currInstr.operands[2] {
-								register = rt
-							}
-							// Store from SP - need to update the offset
-							if rt == registerSP {
-								offset = currInstr.operands[2]
-								register = rt
-							}
-						}
-						return resolveRegisterValue(register, offset, instrIdx-1, seg, childSeg)
-					case 0x08, 0x09, 0x18, 0x19: // add operations
-						if register == rt {
-							// need to check rs carefully
-							// case 1- memory shift of sp(daddi sp, sp, -88)
-							if rs == registerSP {
-								offset += currInstr.operands[2]
-								return resolveRegisterValue(register, offset, instrIdx-1, seg, childSeg)
-							}
-							// case 2- direct assigment to register where rs=registerZero
-							if rs == registerZero {
-								return []*asmparser.Syscall{{
-									Number:      int(currInstr.operands[2]),
-									Segment:     seg,
-									Instruction: currInstr,
-								}}, nil
-							}
-							return nil, fmt.Errorf("not handled modification of register in i-type instruction, instruction:%s", currInstr.Address())
-						}
-					default:
-						return nil, fmt.Errorf("not handled opcode, instruction:%s", currInstr.Address())
-					}
-				}
-			}
-		default:
-		}
-		return resolveRegisterValue(register, offset, instrIdx-1, seg, childSeg)
+// isCallTo reports whether instr transfers control to the start of callee, directly or through a register.
+func (g *callGraph) isCallTo(instr *instruction, callee *segment) bool {
+	//nolint:gosec
+	if instr.isJump() && uint64(instr.jumpTarget()) == callee.address {
+		return true
 	}
+	return g.indirectTargets[instr][callee.address]
+}
 
-	result, err := resolveRegisterValue(registerV0, 0, indexOfInstr-1, s, nil)
-	if err != nil {
-		return nil, err
+func (g *callGraph) IndirectCalls() []*asmparser.IndirectCall {
+	return g.indirect
+}
+
+// buildBlocks splits every segment into basic blocks.
+func (g *callGraph) buildBlocks() {
+	for _, seg := range g.segments {
+		seg.buildBlocks()
 	}
-	return result, nil
+}
+
+func (g *callGraph) addParent(segmentAddr uint64, parentAddr uint64) {
+	seg, exists := g.segments[segmentAddr]
+	if !exists {
+		seg = newSegment(segmentAddr, "")
+	}
+	seg.parents[parentAddr] = true
+	g.segments[segmentAddr] = seg
+}
+
+func (g *callGraph) addSegment(seg *segment) {
+	if existingSeg, exists := g.segments[seg.address]; exists {
+		seg.parents = existingSeg.parents
+		for idx, table := range existingSeg.tables {
+			seg.tables[idx] = table
+		}
+	}
+	g.segments[seg.address] = seg
 }
@@ -1,21 +1,22 @@
 package mips
 
 import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ChainSafe/vm-compat/asmparser"
+	"github.com/ChainSafe/vm-compat/asmparser/elftext/elftexttest"
+	"github.com/ChainSafe/vm-compat/common"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestParse(t *testing.T) {
-	tempFile, err := os.CreateTemp("", "sample.asm")
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer os.Remove(tempFile.Name())
-
 	content := `/sample: file format elf64-tradbigmips
 
 Disassembly of section .text:
@@ -35,15 +36,8 @@ Disassembly of section .text:
    8d9ec:	10 e0 00 02 	beqz	a3,8d9f8 <runtime.read+0x20>
    8d9f0:	00 00 00 0f 	sync
 `
-	if _, err = tempFile.WriteString(content); err != nil {
-		t.Fatal(err)
-	}
-	defer func() {
-		_ = tempFile.Close()
-	}()
-
-	parser := NewParser()
-	graph, err := parser.Parse(tempFile.Name())
+	parser := NewParser(binary.BigEndian)
+	graph, err := parser.Parse(strings.NewReader(content))
 	if err != nil {
 		t.Fatalf("Parse failed: %v", err)
 	}
@@ -114,7 +108,7 @@ Disassembly of section .text:
 	assert.Equal(t, asmparser.IType, instrs[3].Type())
 	assert.Equal(t, "daddiu", instrs[3].Mnemonic())
 
-	syscallNums, err := graph.RetrieveSyscallNum(segment2, instrs[4])
+	syscallNums, _, err := graph.RetrieveSyscallNum(segment2, instrs[4])
 	require.NoError(t, err)
 	assert.Equal(t, 5000, syscallNums[0].Number)
 
@@ -133,13 +127,39 @@ Disassembly of section .text:
 	assert.Equal(t, "sync", instrs[6].Mnemonic())
 }
 
-func TestIndirectSyscall(t *testing.T) {
-	tempFile, err := os.CreateTemp("", "sample.asm")
-	if err != nil {
-		t.Fatal(err)
+func TestDecodeSubFields(t *testing.T) {
+	tests := []struct {
+		word     uint32
+		mnemonic string
+		opcode   string
+		funct    string
+		fields   map[string]string
+	}{
+		{word: 0x04800003, mnemonic: "bltz", opcode: "0x1", fields: map[string]string{"rt": "0x0"}},
+		{word: 0x04810003, mnemonic: "bgez", opcode: "0x1", fields: map[string]string{"rt": "0x1"}},
+		{word: 0x04110003, mnemonic: "bgezal", opcode: "0x1", fields: map[string]string{"rt": "0x11"}}, // bal
+		{word: 0x04900003, mnemonic: "bltzal", opcode: "0x1", fields: map[string]string{"rt": "0x10"}},
+		{word: 0x46241000, mnemonic: "add.d", opcode: "0x11", funct: "0x0", fields: map[string]string{"fmt": "0x11"}},
+		{word: 0x44840000, mnemonic: "mtc1", opcode: "0x11", fields: map[string]string{"fmt": "0x4"}},
+		{word: 0x45010003, mnemonic: "bc1t", opcode: "0x11", fields: map[string]string{"fmt": "0x8", "rt": "0x1"}},
+		{word: 0x7c041420, mnemonic: "seb", opcode: "0x1f", funct: "0x20", fields: map[string]string{"sa": "0x10"}},
+		{word: 0x7c03e83b, mnemonic: "rdhwr", opcode: "0x1f", funct: "0x3b"},
+		{word: 0x27bdfff0, mnemonic: "addiu", opcode: "0x9"},
+		{word: 0x00000000, mnemonic: "sll", opcode: "0x0", funct: "0x0"}, // nop
+		{word: 0x00801025, mnemonic: "or", opcode: "0x0", funct: "0x25"}, // move v0,a0
+		{word: 0x70851002, mnemonic: "mul", opcode: "0x1c", funct: "0x2"},
+		{word: 0x0000000f, mnemonic: "sync", opcode: "0x0", funct: "0xf"},
 	}
-	defer os.Remove(tempFile.Name())
+	for _, tt := range tests {
+		instr := decodeWord(tt.word)
+		assert.Equal(t, tt.mnemonic, instr.BaseMnemonic(), "0x%08x", tt.word)
+		assert.Equal(t, tt.opcode, instr.OpcodeHex(), "0x%08x", tt.word)
+		assert.Equal(t, tt.funct, instr.Funct(), "0x%08x", tt.word)
+		assert.Equal(t, tt.fields, instr.Fields(), "0x%08x", tt.word)
+	}
+}
 
+func TestIndirectSyscall(t *testing.T) {
 	content := `/sample: file format elf64-tradbigmips
 
 Disassembly of section .text:
@@ -185,15 +205,8 @@ Disassembly of section .text:
    1242c:	00 00 18 25 	move	v1,zero
    12430:	00 00 00 0c 	syscall
 `
-	if _, err = tempFile.WriteString(content); err != nil {
-		t.Fatal(err)
-	}
-	defer func() {
-		_ = tempFile.Close()
-	}()
-
-	parser := NewParser()
-	graph, err := parser.Parse(tempFile.Name())
+	parser := NewParser(binary.BigEndian)
+	graph, err := parser.Parse(strings.NewReader(content))
 	if err != nil {
 		t.Fatalf("Parse failed: %v", err)
 	}
@@ -201,7 +214,7 @@ Disassembly of section .text:
 	for _, seg := range graph.Segments() {
 		for _, instr := range seg.Instructions() {
 			if instr.IsSyscall() {
-				res, err := graph.RetrieveSyscallNum(seg, instr)
+				res, _, err := graph.RetrieveSyscallNum(seg, instr)
 				assert.NoError(t, err)
 				syscalls = append(syscalls, res...)
 			}
@@ -209,3 +222,637 @@ Disassembly of section .text:
 	}
 	assert.Equal(t, 2, syscalls[0].Number)
 }
+
+func TestIndirectCalls(t *testing.T) {
+	content := `/sample: file format elf64-tradbigmips
+
+Disassembly of section .text:
+
+0000000000011000 <main.main>:
+   11000: dc 36 00 18  	ld	$22, 24($1)
+   11004: de c1 00 00  	ld	$1, 0($22)
+   11008: 00 20 f8 09  	jalr	$1
+   1100c: dc 22 00 20  	ld	$2, 32($1)
+   11010: 00 40 f8 09  	jalr	$2
+   11014: 00 40 00 08  	jr	$2
+   11018: 03 e0 00 08  	jr	$ra
+`
+	graph, err := NewParser(binary.BigEndian).Parse(strings.NewReader(content))
+	require.NoError(t, err)
+
+	calls := graph.IndirectCalls()
+	require.Len(t, calls, 3)
+	// The code pointer of a closure is the first word of the funcval in the context register
+	assert.Equal(t, "0x11008", calls[0].Instruction.Address())
+	assert.Equal(t, asmparser.IndirectClosure, calls[0].Kind)
+	assert.Equal(t, "0x11010", calls[1].Instruction.Address())
+	assert.Equal(t, asmparser.IndirectUnknown, calls[1].Kind)
+	assert.Equal(t, "0x11014", calls[2].Instruction.Address())
+	// Without the binary there are no candidate targets
+	for _, call := range calls {
+		assert.Equal(t, "main.main", call.Segment.Label())
+		assert.Empty(t, call.Targets)
+	}
+}
+
+func TestJumpTable(t *testing.T) {
+	content := `/sample: file format elf64-tradbigmips
+
+Disassembly of section .text:
+
+0000000000011000 <main.dispatch>:
+   11000: 2c 82 00 03  	sltiu	$2, $4, 3
+   11004: 10 40 00 0a  	beqz	$2, 40 <main.dispatch+0x30>
+   11008: 00 04 10 80  	sll	$2, $4, 2
+   1100c: 3c 03 00 02  	lui	$3, 2
+   11010: 00 43 10 21  	addu	$2, $2, $3
+   11014: 8c 42 00 10  	lw	$2, 16($2)
+   11018: 00 40 00 08  	jr	$2
+   1101c: 24 02 0f a1  	addiu	$2, $zero, 4001
+   11020: 00 00 00 0c  	syscall
+   11024: 03 e0 00 08  	jr	$ra
+   11028: 00 00 00 00  	nop
+   1102c: 00 00 00 00  	nop
+   11030: 03 e0 00 08  	jr	$ra
+   11034: 00 00 00 00  	nop
+
+0000000000012000 <main.other>:
+   12000: 03 e0 00 08  	jr	$ra
+   12004: 00 00 00 00  	nop
+`
+	// The table at 0x20010 jumps within main.dispatch and to main.other, and ends at the null entry
+	table := make([]byte, 16)
+	for _, entry := range []uint32{0x11020, 0x11030, 0x12000, 0} {
+		table = binary.BigEndian.AppendUint32(table, entry)
+	}
+	data := elftexttest.DataELF(t, elf.EM_MIPS, binary.BigEndian, 0x20000, table)
+	graph, err := NewParser(binary.BigEndian, asmparser.WithBinary(data)).Parse(strings.NewReader(content))
+	require.NoError(t, err)
+
+	calls := graph.IndirectCalls()
+	require.Len(t, calls, 1)
+	call := calls[0]
+	assert.Equal(t, "0x11018", call.Instruction.Address())
+	assert.Equal(t, asmparser.IndirectJumpTable, call.Kind)
+	require.Len(t, call.Targets, 1)
+	assert.Equal(t, "main.other", call.Targets[0].Label())
+	require.Len(t, call.Blocks, 2)
+	assert.Equal(t, "0x11020", call.Blocks[0].Address())
+	assert.Equal(t, "0x11030", call.Blocks[1].Address())
+	assert.True(t, call.Resolved())
+
+	var dispatch, other asmparser.Segment
+	for _, seg := range graph.Segments() {
+		switch seg.Label() {
+		case "main.dispatch":
+			dispatch = seg
+		case "main.other":
+			other = seg
+		}
+	}
+	require.NotNil(t, dispatch)
+	require.NotNil(t, other)
+	require.Len(t, graph.ParentsOf(other), 1)
+	assert.Equal(t, dispatch, graph.ParentsOf(other)[0])
+
+	// The targets are successors of the block of the jump, the syscall is only reached through the table
+	blocks := dispatch.Blocks()
+	require.Len(t, blocks, 5)
+	assert.Equal(t, "0x1100c", blocks[1].Address())
+	assert.ElementsMatch(t, call.Blocks, blocks[1].Successors())
+	syscalls, _, err := graph.RetrieveSyscallNum(dispatch, dispatch.Instructions()[8])
+	require.NoError(t, err)
+	require.Len(t, syscalls, 1)
+	assert.Equal(t, 4001, syscalls[0].Number)
+
+	// Without the binary the table is not read
+	graph, err = NewParser(binary.BigEndian).Parse(strings.NewReader(content))
+	require.NoError(t, err)
+	require.Len(t, graph.IndirectCalls(), 1)
+	assert.Equal(t, asmparser.IndirectUnknown, graph.IndirectCalls()[0].Kind)
+	assert.False(t, graph.IndirectCalls()[0].Resolved())
+}
+
+func TestAddSegment(t *testing.T) {
+	graph := newCallGraph()
+	// A jump to a function not parsed yet creates a placeholder segment
+	graph.addParent(0x12000, 0x11000)
+	placeholder := graph.segments[0x12000]
+	require.NotNil(t, placeholder)
+	require.NotNil(t, placeholder.tables)
+	placeholder.tables[3] = []uint64{0x12010}
+
+	// The parsed function keeps the parents and tables of the placeholder
+	seg := newSegment(0x12000, "main.f")
+	graph.addSegment(seg)
+	assert.Same(t, seg, graph.segments[0x12000])
+	assert.Equal(t, map[uint64]bool{0x11000: true}, seg.parents)
+	assert.Equal(t, map[int][]uint64{3: {0x12010}}, seg.tables)
+}
+
+func TestBlocks(t *testing.T) {
+	content := `/sample: file format elf64-tradbigmips
+
+Disassembly of section .text:
+
+0000000000011000 <main.main>:
+   11000: 64 02 13 89  	daddiu	$2, $zero, 5001
+   11004: 10 00 00 04  	b	20 <main.main+0x18>
+   11008: 00 00 00 00  	nop
+   1100c: 64 02 27 0f  	daddiu	$2, $zero, 9999
+   11010: 03 e0 00 08  	jr	$ra
+   11014: 00 00 00 00  	nop
+   11018: 00 00 00 0c  	syscall
+   1101c: 14 80 ff fe  	bnez	$4, -4 <main.main+0x18>
+   11020: 00 00 00 00  	nop
+   11024: 03 e0 00 08  	jr	$ra
+   11028: 00 00 00 00  	nop
+`
+	graph, err := NewParser(binary.BigEndian).Parse(strings.NewReader(content))
+	require.NoError(t, err)
+	require.Len(t, graph.Segments(), 1)
+	seg := graph.Segments()[0]
+
+	// Blocks end after the delay slot of a jump or branch, and start at branch targets
+	blocks := seg.Blocks()
+	addresses := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		addresses = append(addresses, b.Address())
+	}
+	require.Equal(t, []string{"0x11000", "0x1100c", "0x11018", "0x11024"}, addresses)
+	assert.Len(t, blocks[0].Instructions(), 3)
+
+	successors := func(b asmparser.BasicBlock) []string {
+		result := make([]string, 0)
+		for _, succ := range b.Successors() {
+			result = append(result, succ.Address())
+		}
+		return result
+	}
+	assert.Equal(t, []string{"0x11018"}, successors(blocks[0]))
+	assert.Empty(t, successors(blocks[1]))
+	assert.Equal(t, []string{"0x11018", "0x11024"}, successors(blocks[2]))
+	assert.Empty(t, successors(blocks[3]))
+	assert.Len(t, blocks[2].Predecessors(), 2)
+
+	// The syscall number only comes from the paths that reach the syscall
+	syscalls, _, err := graph.RetrieveSyscallNum(seg, seg.Instructions()[6])
+	require.NoError(t, err)
+	require.Len(t, syscalls, 1)
+	assert.Equal(t, 5001, syscalls[0].Number)
+	assert.Equal(t, "0x11000", syscalls[0].Instruction.Address())
+}
+
+func TestRetrieveSyscallNum(t *testing.T) {
+	content := `/sample: file format elf64-tradbigmips
+
+Disassembly of section .text:
+
+0000000000011000 <main.lui>:
+   11000: 3c 01 00 00  	lui	$1, 0
+   11004: 34 21 13 89  	ori	$1, $1, 5001
+   11008: ff a1 00 08  	sd	$1, 8($sp)
+   1100c: 0c 00 44 40  	jal	69888 <syscall.Syscall6>
+   11010: 00 00 00 00  	nop
+   11014: 03 e0 00 08  	jr	$ra
+   11018: 00 00 00 00  	nop
+
+000000000001101c <main.delaySlot>:
+   1101c: 64 03 13 8a  	daddiu	$3, $zero, 5002
+   11020: 0c 00 44 40  	jal	69888 <syscall.Syscall6>
+   11024: ff a3 00 08  	sd	$3, 8($sp)
+   11028: 03 e0 00 08  	jr	$ra
+   1102c: 00 00 00 00  	nop
+
+0000000000011030 <main.large>:
+   11030: 3c 01 00 01  	lui	$1, 1
+   11034: 34 21 00 05  	ori	$1, $1, 5
+   11038: 0c 00 44 40  	jal	69888 <syscall.Syscall6>
+   1103c: ff a1 00 08  	sd	$1, 8($sp)
+
+0000000000011040 <main.clobbered>:
+   11040: 64 01 13 8b  	daddiu	$1, $zero, 5003
+   11044: 0c 00 44 50  	jal	69952 <main.other>
+   11048: 00 00 00 00  	nop
+   1104c: 0c 00 44 40  	jal	69888 <syscall.Syscall6>
+   11050: ff a1 00 08  	sd	$1, 8($sp)
+
+0000000000011100 <syscall.Syscall6>:
+   11100: df a5 00 08  	ld	$5, 8($sp)
+   11104: 00 a0 10 25  	move	$2, $5
+   11108: 10 40 00 02  	beqz	$2, 12 <syscall.Syscall6+0x14>
+   1110c: 00 00 00 00  	nop
+   11110: 00 00 00 0c  	syscall
+   11114: 03 e0 00 08  	jr	$ra
+   11118: 00 00 00 00  	nop
+
+0000000000011140 <main.other>:
+   11140: 03 e0 00 08  	jr	$ra
+   11144: 00 00 00 00  	nop
+`
+	graph, err := NewParser(binary.BigEndian).Parse(strings.NewReader(content))
+	require.NoError(t, err)
+
+	var syscall6 asmparser.Segment
+	for _, seg := range graph.Segments() {
+		if seg.Label() == "syscall.Syscall6" {
+			syscall6 = seg
+		}
+	}
+	require.NotNil(t, syscall6)
+	syscalls, unresolved, err := graph.RetrieveSyscallNum(syscall6, syscall6.Instructions()[4])
+	require.NoError(t, err)
+
+	origins := make(map[int]string)
+	for _, syscall := range syscalls {
+		origins[syscall.Number] = syscall.Segment.Label() + "@" + syscall.Instruction.Address()
+	}
+	// The value set before the call to main.other is clobbered by the call
+	assert.Equal(t, map[int]string{
+		5001:    "main.lui@0x11000",
+		5002:    "main.delaySlot@0x1101c",
+		0x10005: "main.large@0x11030",
+	}, origins)
+	require.Len(t, unresolved, 1)
+	assert.Equal(t, asmparser.UnresolvedCall, unresolved[0].Reason)
+	assert.Equal(t, "main.clobbered", unresolved[0].Segment.Label())
+	assert.Equal(t, "0x11044", unresolved[0].Instruction.Address())
+}
+
+func TestUnresolvedSyscallNum(t *testing.T) {
+	content := `/sample: file format elf64-tradbigmips
+
+Disassembly of section .text:
+
+0000000000011000 <main.main>:
+   11000: 10 80 00 04  	beqz	$4, 20 <main.main+0x14>
+   11004: 00 00 00 00  	nop
+   11008: dc 82 00 10  	ld	$2, 16($4)
+   1100c: 10 00 00 03  	b	16 <main.main+0x1c>
+   11010: 00 00 00 00  	nop
+   11014: 63 bd ff f0  	daddi	$sp, $sp, -16
+   11018: df a2 00 08  	ld	$2, 8($sp)
+   1101c: 00 00 00 0c  	syscall
+   11020: 03 e0 00 08  	jr	$ra
+   11024: 00 00 00 00  	nop
+
+0000000000011100 <main.entry>:
+   11100: 00 00 00 0c  	syscall
+   11104: 03 e0 00 08  	jr	$ra
+   11108: 00 00 00 00  	nop
+`
+	graph, err := NewParser(binary.BigEndian).Parse(strings.NewReader(content))
+	require.NoError(t, err)
+
+	reasons := make(map[string]asmparser.UnresolvedReason)
+	for _, seg := range graph.Segments() {
+		for _, instr := range seg.Instructions() {
+			if !instr.IsSyscall() {
+				continue
+			}
+			syscalls, unresolved, err := graph.RetrieveSyscallNum(seg, instr)
+			require.NoError(t, err)
+			assert.Empty(t, syscalls)
+			for _, u := range unresolved {
+				reasons[u.Segment.Label()+"@"+u.Instruction.Address()] = u.Reason
+			}
+		}
+	}
+	assert.Equal(t, map[string]asmparser.UnresolvedReason{
+		// Loaded from memory other than the stack
+		"main.main@0x11008": asmparser.UnresolvedDynamic,
+		// The stack slot is relative to the caller's sp, and main.main has no caller
+		"main.main@0x11000": asmparser.UnresolvedNoCaller,
+		// The value is held on entry of a function nothing calls
+		"main.entry@0x11100": asmparser.UnresolvedNoCaller,
+	}, reasons)
+}
+
+func TestDelaySlots(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected map[string][]int // Syscall numbers by address of the syscall instruction.
+		// Reasons the numbers could not be determined, by address of the instruction they were lost at.
+		unresolved map[string]asmparser.UnresolvedReason
+	}{
+		{
+			// Generated by Go 1.27 for mips64, the delay slots hold nops
+			name: "go",
+			content: `
+0000000000020e80 <internal/runtime/syscall/linux.Pread>:
+   20e80: df c1 00 10  	ld	$1, 16($fp)
+   20e84: 00 3d 08 2b  	sltu	$1, $1, $sp
+   20e88: 14 20 00 06  	bnez	$1, 28 <internal/runtime/syscall/linux.Pread+0x24>
+   20e8c: 00 00 00 00  	nop
+   20e90: 00 1f 18 25  	or	$3, $zero, $ra
+   20e94: 0c 02 ed a0  	jal	767616 <runtime.morestack_noctxt>
+   20e98: 00 00 00 00  	nop
+   20e9c: 10 00 ff f8  	b	-28 <internal/runtime/syscall/linux.Pread>
+   20ea0: 00 00 00 00  	nop
+   20ea4: ff bf ff a0  	sd	$ra, -96($sp)
+   20ea8: 63 bd ff a0  	daddi	$sp, $sp, -96
+   20eac: ff bf 00 00  	sd	$ra, 0($sp)
+   20eb0: df a1 00 78  	ld	$1, 120($sp)
+   20eb4: 10 20 00 04  	beqz	$1, 20 <internal/runtime/syscall/linux.Pread+0x48>
+   20eb8: 00 00 00 00  	nop
+   20ebc: df a2 00 70  	ld	$2, 112($sp)
+   20ec0: 10 00 00 04  	b	20 <internal/runtime/syscall/linux.Pread+0x54>
+   20ec4: 00 00 00 00  	nop
+   20ec8: 3c 02 00 1b  	lui	$2, 27
+   20ecc: 00 5c 10 2d  	daddu	$2, $2, $gp
+   20ed0: 64 42 a6 d8  	daddiu	$2, $2, -22824
+   20ed4: ff a2 00 58  	sd	$2, 88($sp)
+   20ed8: 64 03 13 98  	daddiu	$3, $zero, 5016
+   20edc: ff a3 00 08  	sd	$3, 8($sp)
+   20ee0: df a3 00 68  	ld	$3, 104($sp)
+   20ee4: ff a3 00 10  	sd	$3, 16($sp)
+   20ee8: ff a2 00 18  	sd	$2, 24($sp)
+   20eec: ff a1 00 20  	sd	$1, 32($sp)
+   20ef0: df a1 00 88  	ld	$1, 136($sp)
+   20ef4: ff a1 00 28  	sd	$1, 40($sp)
+   20ef8: ff a0 00 30  	sd	$zero, 48($sp)
+   20efc: ff a0 00 38  	sd	$zero, 56($sp)
+   20f00: 0c 00 83 ca  	jal	134952 <internal/runtime/syscall/linux.Syscall6>
+   20f04: 00 00 00 00  	nop
+   20f08: df a1 00 40  	ld	$1, 64($sp)
+   20f0c: df a2 00 50  	ld	$2, 80($sp)
+   20f10: ff a1 00 90  	sd	$1, 144($sp)
+   20f14: ff a2 00 98  	sd	$2, 152($sp)
+   20f18: df bf 00 00  	ld	$ra, 0($sp)
+   20f1c: 63 bd 00 60  	daddi	$sp, $sp, 96
+   20f20: 03 e0 00 08  	jr	$ra
+   20f24: 00 00 00 00  	nop
+
+0000000000020f28 <internal/runtime/syscall/linux.Syscall6>:
+   20f28: df a2 00 08  	ld	$2, 8($sp)
+   20f2c: df a4 00 10  	ld	$4, 16($sp)
+   20f30: df a5 00 18  	ld	$5, 24($sp)
+   20f34: df a6 00 20  	ld	$6, 32($sp)
+   20f38: df a7 00 28  	ld	$7, 40($sp)
+   20f3c: df a8 00 30  	ld	$8, 48($sp)
+   20f40: df a9 00 38  	ld	$9, 56($sp)
+   20f44: 00 00 18 25  	move	$3, $zero
+   20f48: 00 00 00 0c  	syscall
+   20f4c: 10 e0 00 07  	beqz	$7, 32 <internal/runtime/syscall/linux.Syscall6+0x44>
+   20f50: 00 00 00 00  	nop
+`,
+			expected: map[string][]int{"0x20f48": {5016}},
+		},
+		{
+			// Generated by Go 1.27 for mips, llvm-objdump prints the zero words after the
+			// branch at 0x230d4 as "...", its delay slot is the first of them
+			name: "zeroes",
+			content: `
+00021468 <internal/runtime/syscall/linux.Syscall6>:
+   21468: af bf ff e8  	sw	$ra, -24($sp)
+   2146c: 27 bd ff e8  	addiu	$sp, $sp, -24
+   21470: af bf 00 00  	sw	$ra, 0($sp)
+   21474: 8f a2 00 1c  	lw	$2, 28($sp)
+   21478: 8f a4 00 20  	lw	$4, 32($sp)
+   2147c: 8f a5 00 24  	lw	$5, 36($sp)
+   21480: 8f a6 00 28  	lw	$6, 40($sp)
+   21484: 8f a7 00 2c  	lw	$7, 44($sp)
+   21488: 8f a8 00 30  	lw	$8, 48($sp)
+   2148c: 8f a9 00 34  	lw	$9, 52($sp)
+   21490: af a8 00 10  	sw	$8, 16($sp)
+   21494: af a9 00 14  	sw	$9, 20($sp)
+   21498: 00 00 18 25  	move	$3, $zero
+   2149c: 00 00 00 0c  	syscall
+   214a0: 10 e0 00 08  	beqz	$7, 36 <internal/runtime/syscall/linux.Syscall6+0x5c>
+   214a4: 00 00 00 00  	nop
+
+0002305c <internal/runtime/cgroup.CPU.Close>:
+   2305c: 8f c1 00 08  	lw	$1, 8($fp)
+   23060: 00 3d 08 2b  	sltu	$1, $1, $sp
+   23064: 14 20 00 06  	bnez	$1, 28 <internal/runtime/cgroup.CPU.Close+0x24>
+   23068: 00 00 00 00  	nop
+   2306c: 00 1f 18 25  	or	$3, $zero, $ra
+   23070: 0c 02 e2 da  	jal	756584 <runtime.morestack_noctxt>
+   23074: 00 00 00 00  	nop
+   23078: 10 00 ff f8  	b	-28 <internal/runtime/cgroup.CPU.Close>
+   2307c: 00 00 00 00  	nop
+   23080: af bf ff d4  	sw	$ra, -44($sp)
+   23084: 27 bd ff d4  	addiu	$sp, $sp, -44
+   23088: af bf 00 00  	sw	$ra, 0($sp)
+   2308c: 8f a1 00 30  	lw	$1, 48($sp)
+   23090: 38 22 00 01  	xori	$2, $1, 1
+   23094: 10 40 00 11  	beqz	$2, 72 <internal/runtime/cgroup.CPU.Close+0x80>
+   23098: 00 00 00 00  	nop
+   2309c: 38 21 00 02  	xori	$1, $1, 2
+   230a0: 14 20 00 2a  	bnez	$1, 172 <internal/runtime/cgroup.CPU.Close+0xf0>
+   230a4: 00 00 00 00  	nop
+   230a8: 24 01 0f a6  	addiu	$1, $zero, 4006
+   230ac: af a1 00 04  	sw	$1, 4($sp)
+   230b0: 8f a1 00 34  	lw	$1, 52($sp)
+   230b4: af a1 00 08  	sw	$1, 8($sp)
+   230b8: af a0 00 0c  	sw	$zero, 12($sp)
+   230bc: af a0 00 10  	sw	$zero, 16($sp)
+   230c0: af a0 00 14  	sw	$zero, 20($sp)
+   230c4: af a0 00 18  	sw	$zero, 24($sp)
+   230c8: af a0 00 1c  	sw	$zero, 28($sp)
+   230cc: 0c 00 85 1a  	jal	136296 <internal/runtime/syscall/linux.Syscall6>
+   230d0: 00 00 00 00  	nop
+   230d4: 10 00 00 19  	b	104 <internal/runtime/cgroup.CPU.Close+0xe0>
+		...
+   230e0: 24 01 0f a6  	addiu	$1, $zero, 4006
+   230e4: af a1 00 04  	sw	$1, 4($sp)
+   230e8: 8f a1 00 34  	lw	$1, 52($sp)
+   230ec: af a1 00 08  	sw	$1, 8($sp)
+   230f0: af a0 00 0c  	sw	$zero, 12($sp)
+   230f4: af a0 00 10  	sw	$zero, 16($sp)
+   230f8: af a0 00 14  	sw	$zero, 20($sp)
+   230fc: af a0 00 18  	sw	$zero, 24($sp)
+   23100: af a0 00 1c  	sw	$zero, 28($sp)
+   23104: 0c 00 85 1a  	jal	136296 <internal/runtime/syscall/linux.Syscall6>
+		...
+   23110: 24 01 0f a6  	addiu	$1, $zero, 4006
+   23114: af a1 00 04  	sw	$1, 4($sp)
+   23118: 8f a1 00 38  	lw	$1, 56($sp)
+   2311c: af a1 00 08  	sw	$1, 8($sp)
+   23120: af a0 00 0c  	sw	$zero, 12($sp)
+   23124: af a0 00 10  	sw	$zero, 16($sp)
+   23128: af a0 00 14  	sw	$zero, 20($sp)
+   2312c: af a0 00 18  	sw	$zero, 24($sp)
+   23130: af a0 00 1c  	sw	$zero, 28($sp)
+   23134: 0c 00 85 1a  	jal	136296 <internal/runtime/syscall/linux.Syscall6>
+   23138: 00 00 00 00  	nop
+   2313c: 8f bf 00 00  	lw	$ra, 0($sp)
+   23140: 27 bd 00 2c  	addiu	$sp, $sp, 44
+   23144: 03 e0 00 08  	jr	$ra
+   23148: 00 00 00 00  	nop
+`,
+			expected: map[string][]int{"0x2149c": {4006, 4006, 4006}},
+		},
+		{
+			// $v0 holds the result of the call, not the value set in its delay slot
+			name: "call",
+			content: `
+0000000000011000 <main.main>:
+   11000: 64 02 0f a1  	daddiu	$2, $zero, 4001
+   11004: 0c 00 44 40  	jal	69888 <main.other>
+   11008: 64 02 0f a2  	daddiu	$2, $zero, 4002
+   1100c: 00 00 00 0c  	syscall
+
+0000000000011100 <main.other>:
+   11100: 03 e0 00 08  	jr	$ra
+   11104: 00 00 00 00  	nop
+`,
+			expected:   map[string][]int{"0x1100c": {}},
+			unresolved: map[string]asmparser.UnresolvedReason{"0x11004": asmparser.UnresolvedCall},
+		},
+		{
+			// The delay slot of a branch likely is annulled when the branch is not taken
+			name: "likely",
+			content: `
+0000000000011000 <main.main>:
+   11000: 64 02 0f a1  	daddiu	$2, $zero, 4001
+   11004: 50 80 00 04  	beqzl	$4, 20 <main.main+0x18>
+   11008: 64 02 0f a2  	daddiu	$2, $zero, 4002
+   1100c: 00 00 00 0c  	syscall
+   11010: 03 e0 00 08  	jr	$ra
+   11014: 00 00 00 00  	nop
+   11018: 00 00 00 0c  	syscall
+   1101c: 03 e0 00 08  	jr	$ra
+   11020: 00 00 00 00  	nop
+`,
+			expected: map[string][]int{"0x1100c": {4001}, "0x11018": {4002}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph, err := NewParser(binary.BigEndian).Parse(strings.NewReader(tt.content))
+			require.NoError(t, err)
+
+			numbers := make(map[string][]int)
+			var reasons map[string]asmparser.UnresolvedReason
+			for _, seg := range graph.Segments() {
+				for _, instr := range seg.Instructions() {
+					if !instr.IsSyscall() {
+						continue
+					}
+					syscalls, unresolved, err := graph.RetrieveSyscallNum(seg, instr)
+					require.NoError(t, err)
+					numbers[instr.Address()] = make([]int, 0)
+					for _, syscall := range syscalls {
+						numbers[instr.Address()] = append(numbers[instr.Address()], syscall.Number)
+					}
+					for _, u := range unresolved {
+						if reasons == nil {
+							reasons = make(map[string]asmparser.UnresolvedReason)
+						}
+						reasons[u.Instruction.Address()] = u.Reason
+					}
+				}
+			}
+			assert.Equal(t, tt.expected, numbers)
+			assert.Equal(t, tt.unresolved, reasons)
+		})
+	}
+}
+
+func TestParseLittleEndian(t *testing.T) {
+	content := `/sample:	file format elf64-mips
+
+Disassembly of section .text:
+
+00000000000be498 <runtime.exit>:
+   be498: 08 00 a4 8f  	lw	$4, 8($sp)
+   be49c: 55 14 02 64  	daddiu	$2, $zero, 5205 <go.go+0x1455>
+   be4a0: 0c 00 00 00  	syscall <go.go>
+   be4a4: 08 00 e0 03  	jr	$ra
+`
+	graph, err := NewParser(binary.LittleEndian).Parse(strings.NewReader(content))
+	require.NoError(t, err)
+	require.Len(t, graph.Segments(), 1)
+
+	seg := graph.Segments()[0]
+	instrs := seg.Instructions()
+	require.Len(t, instrs, 4)
+	assert.Equal(t, "0x23", instrs[0].OpcodeHex())
+	assert.True(t, instrs[2].IsSyscall())
+	assert.Equal(t, "0xc", instrs[2].Funct())
+	assert.Equal(t, "0x8", instrs[3].Funct())
+
+	syscalls, _, err := graph.RetrieveSyscallNum(seg, instrs[2])
+	require.NoError(t, err)
+	require.Len(t, syscalls, 1)
+	assert.Equal(t, 5205, syscalls[0].Number)
+}
+
+func TestParseELF(t *testing.T) {
+	tests := []struct {
+		name  string
+		arch  string
+		cfg   *common.BuildConfig
+		first string // Go functions start by loading the stack guard from g
+	}{
+		{name: "mips64", arch: "mips64", first: "ld"},
+		// Stripped binaries have no symbol table, functions come from the Go line table
+		{name: "stripped", arch: "mips64", cfg: &common.BuildConfig{LDFlags: "-s -w"}, first: "ld"},
+		{name: "mips64le", arch: "mips64le", first: "ld"},
+		{name: "mipsle", arch: "mipsle", first: "lw"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "sample")
+			require.NoError(t, common.BuildBinary("../../examples/sample.go", "linux", tt.arch, path, tt.cfg))
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			// The byte order of the ELF header takes precedence
+			graph, err := NewParser(binary.BigEndian).Parse(bytes.NewReader(data))
+			require.NoError(t, err)
+
+			var mainSegment asmparser.Segment
+			syscalls := 0
+			for _, seg := range graph.Segments() {
+				if seg.Label() == "main.main" {
+					mainSegment = seg
+				}
+				for _, instr := range seg.Instructions() {
+					if instr.IsSyscall() {
+						assert.Equal(t, "0x0", instr.OpcodeHex())
+						assert.Equal(t, "0xc", instr.Funct())
+						syscalls++
+					}
+				}
+			}
+			require.NotNil(t, mainSegment)
+			assert.Greater(t, syscalls, 0)
+			assertIndirectCalls(t, graph)
+
+			instrs := mainSegment.Instructions()
+			require.NotEmpty(t, instrs)
+			assert.Equal(t, mainSegment.Address(), instrs[0].Address())
+			assert.Equal(t, tt.first, instrs[0].Mnemonic())
+			assert.Equal(t, asmparser.IType, instrs[0].Type())
+
+			// The blocks partition the instructions of the function
+			covered := 0
+			for _, b := range mainSegment.Blocks() {
+				covered += len(b.Instructions())
+			}
+			assert.Greater(t, len(mainSegment.Blocks()), 1)
+			assert.Equal(t, len(instrs), covered)
+		})
+	}
+}
+
+// assertIndirectCalls checks that both interface method and func value calls of a Go
+// binary are resolved to functions that have the caller as parent.
+func assertIndirectCalls(t *testing.T, graph asmparser.CallGraph) {
+	t.Helper()
+	resolved := make(map[asmparser.IndirectKind]bool)
+	for _, call := range graph.IndirectCalls() {
+		if len(call.Targets) == 0 {
+			continue
+		}
+		resolved[call.Kind] = true
+		target := call.Targets[0]
+		assert.Contains(t, graph.ParentsOf(target), call.Segment)
+		assert.Contains(t, graph.CallSites(call.Segment, target), call.Instruction)
+	}
+	assert.True(t, resolved[asmparser.IndirectItab])
+	assert.True(t, resolved[asmparser.IndirectClosure])
+}
@@ -0,0 +1,163 @@
+package mips
+
+// Mnemonic tables used to name instructions decoded straight from a binary.
+// Pseudo instructions are only rendered for the common cases emitted by the Go toolchain.
+var (
+	opcodeMnemonics = map[uint32]string{
+		0x02: "j", 0x03: "jal", 0x04: "beq", 0x05: "bne", 0x06: "blez", 0x07: "bgtz",
+		0x08: "addi", 0x09: "addiu", 0x0a: "slti", 0x0b: "sltiu", 0x0c: "andi", 0x0d: "ori",
+		0x0e: "xori", 0x0f: "lui", 0x10: "cop0", 0x12: "cop2", 0x13: "cop1x",
+		0x14: "beql", 0x15: "bnel", 0x16: "blezl", 0x17: "bgtzl", 0x18: "daddi", 0x19: "daddiu",
+		0x1a: "ldl", 0x1b: "ldr", 0x1e: "msa", 0x20: "lb", 0x21: "lh", 0x22: "lwl", 0x23: "lw",
+		0x24: "lbu", 0x25: "lhu", 0x26: "lwr", 0x27: "lwu", 0x28: "sb", 0x29: "sh", 0x2a: "swl",
+		0x2b: "sw", 0x2c: "sdl", 0x2d: "sdr", 0x2e: "swr", 0x2f: "cache", 0x30: "ll", 0x31: "lwc1",
+		0x32: "lwc2", 0x33: "pref", 0x34: "lld", 0x35: "ldc1", 0x36: "ldc2", 0x37: "ld", 0x38: "sc",
+		0x39: "swc1", 0x3a: "swc2", 0x3c: "scd", 0x3d: "sdc1", 0x3e: "sdc2", 0x3f: "sd",
+	}
+
+	// specialMnemonics maps the funct field of SPECIAL (opcode 0x0) instructions.
+	specialMnemonics = map[uint32]string{
+		0x00: "sll", 0x01: "movci", 0x02: "srl", 0x03: "sra", 0x04: "sllv", 0x06: "srlv", 0x07: "srav",
+		0x08: "jr", 0x09: "jalr", 0x0a: "movz", 0x0b: "movn", 0x0c: "syscall", 0x0d: "break", 0x0f: "sync",
+		0x10: "mfhi", 0x11: "mthi", 0x12: "mflo", 0x13: "mtlo", 0x14: "dsllv", 0x16: "dsrlv", 0x17: "dsrav",
+		0x18: "mult", 0x19: "multu", 0x1a: "div", 0x1b: "divu", 0x1c: "dmult", 0x1d: "dmultu", 0x1e: "ddiv",
+		0x1f: "ddivu", 0x20: "add", 0x21: "addu", 0x22: "sub", 0x23: "subu", 0x24: "and", 0x25: "or",
+		0x26: "xor", 0x27: "nor", 0x2a: "slt", 0x2b: "sltu", 0x2c: "dadd", 0x2d: "daddu", 0x2e: "dsub",
+		0x2f: "dsubu", 0x30: "tge", 0x31: "tgeu", 0x32: "tlt", 0x33: "tltu", 0x34: "teq", 0x36: "tne",
+		0x38: "dsll", 0x3a: "dsrl", 0x3b: "dsra", 0x3c: "dsll32", 0x3e: "dsrl32", 0x3f: "dsra32",
+	}
+
+	// special2Mnemonics maps the funct field of SPECIAL2 (opcode 0x1c) instructions.
+	special2Mnemonics = map[uint32]string{
+		0x00: "madd", 0x01: "maddu", 0x02: "mul", 0x04: "msub", 0x05: "msubu",
+		0x20: "clz", 0x21: "clo", 0x24: "dclz", 0x25: "dclo", 0x3f: "sdbbp",
+	}
+
+	// special3Mnemonics maps the funct field of SPECIAL3 (opcode 0x1f) instructions.
+	special3Mnemonics = map[uint32]string{
+		0x00: "ext", 0x01: "dextm", 0x02: "dextu", 0x03: "dext", 0x04: "ins", 0x05: "dinsm", 0x06: "dinsu",
+		0x07: "dins", 0x3b: "rdhwr",
+	}
+
+	// bshflMnemonics and dbshflMnemonics map the sa field of the SPECIAL3 bshfl (funct 0x20)
+	// and dbshfl (funct 0x24) instructions.
+	bshflMnemonics  = map[uint32]string{0x02: "wsbh", 0x10: "seb", 0x18: "seh"}
+	dbshflMnemonics = map[uint32]string{0x02: "dsbh", 0x05: "dshd"}
+
+	// regimmMnemonics maps the rt field of REGIMM (opcode 0x1) instructions.
+	regimmMnemonics = map[uint32]string{
+		0x00: "bltz", 0x01: "bgez", 0x02: "bltzl", 0x03: "bgezl", 0x08: "tgei", 0x09: "tgeiu",
+		0x0a: "tlti", 0x0b: "tltiu", 0x0c: "teqi", 0x0e: "tnei", 0x10: "bltzal", 0x11: "bgezal",
+		0x12: "bltzall", 0x13: "bgezall", 0x1f: "synci",
+	}
+
+	// cop1Mnemonics maps the fmt field of the COP1 (opcode 0x11) moves and branches.
+	cop1Mnemonics = map[uint32]string{
+		0x00: "mfc1", 0x01: "dmfc1", 0x02: "cfc1", 0x03: "mfhc1", 0x04: "mtc1", 0x05: "dmtc1", 0x06: "ctc1",
+		0x07: "mthc1",
+	}
+
+	// cop1FmtSuffixes maps the fmt field of COP1 arithmetic to the operand format suffix.
+	cop1FmtSuffixes = map[uint32]string{0x10: "s", 0x11: "d", 0x14: "w", 0x15: "l", 0x16: "ps"}
+
+	// cop1ArithMnemonics maps the funct field of COP1 arithmetic, the format suffix is appended.
+	cop1ArithMnemonics = map[uint32]string{
+		0x00: "add", 0x01: "sub", 0x02: "mul", 0x03: "div", 0x04: "sqrt", 0x05: "abs", 0x06: "mov", 0x07: "neg",
+		0x08: "round.l", 0x09: "trunc.l", 0x0a: "ceil.l", 0x0b: "floor.l", 0x0c: "round.w", 0x0d: "trunc.w",
+		0x0e: "ceil.w", 0x0f: "floor.w", 0x11: "movcf", 0x12: "movz", 0x13: "movn", 0x20: "cvt.s", 0x21: "cvt.d",
+		0x24: "cvt.w", 0x25: "cvt.l", 0x30: "c.f", 0x31: "c.un", 0x32: "c.eq", 0x33: "c.ueq", 0x34: "c.olt",
+		0x35: "c.ult", 0x36: "c.ole", 0x37: "c.ule", 0x38: "c.sf", 0x39: "c.ngle", 0x3a: "c.seq", 0x3b: "c.ngl",
+		0x3c: "c.lt", 0x3d: "c.nge", 0x3e: "c.le", 0x3f: "c.ngt",
+	}
+)
+
+// mnemonicOf returns the assembly mnemonic of a raw MIPS instruction word,
+// rendering the pseudo instructions the Go toolchain commonly emits.
+func mnemonicOf(word uint32) string {
+	opcode := (word >> 26) & 0x3F
+	rs := (word >> 21) & 0x1F
+	rt := (word >> 16) & 0x1F
+	rd := (word >> 11) & 0x1F
+	funct := word & 0x3F
+
+	switch opcode {
+	case 0x00:
+		switch {
+		case word == 0:
+			return "nop"
+		case (funct == 0x25 || funct == 0x21 || funct == 0x2d) && rt == registerZero && rd != registerZero:
+			return "move"
+		}
+	case 0x01:
+		if rt == 0x11 && rs == registerZero {
+			return "bal"
+		}
+	case 0x04:
+		switch {
+		case rs == registerZero && rt == registerZero:
+			return "b"
+		case rt == registerZero:
+			return "beqz"
+		}
+	case 0x05:
+		if rt == registerZero {
+			return "bnez"
+		}
+	}
+	return baseMnemonicOf(word)
+}
+
+// baseMnemonicOf returns the mnemonic of the operation selected by the opcode, funct and
+// sub-fields of a raw MIPS instruction word, without pseudo instruction aliases.
+func baseMnemonicOf(word uint32) string {
+	opcode := (word >> 26) & 0x3F
+	rs := (word >> 21) & 0x1F
+	rt := (word >> 16) & 0x1F
+	sa := (word >> 6) & 0x1F
+	funct := word & 0x3F
+
+	var name string
+	switch opcode {
+	case 0x00:
+		name = specialMnemonics[funct]
+	case 0x01:
+		name = regimmMnemonics[rt]
+	case 0x11:
+		name = cop1Mnemonic(rs, rt, funct)
+	case 0x1c:
+		name = special2Mnemonics[funct]
+	case 0x1f:
+		switch funct {
+		case 0x20:
+			name = bshflMnemonics[sa]
+		case 0x24:
+			name = dbshflMnemonics[sa]
+		default:
+			name = special3Mnemonics[funct]
+		}
+	default:
+		name = opcodeMnemonics[opcode]
+	}
+	if name == "" {
+		return "unknown"
+	}
+	return name
+}
+
+// cop1Mnemonic names a COP1 instruction from its fmt (rs), rt and funct fields.
+func cop1Mnemonic(format, rt, funct uint32) string {
+	switch {
+	case format == 0x08:
+		if rt&0x1 == 0 {
+			return "bc1f"
+		}
+		return "bc1t"
+	case format < 0x10:
+		return cop1Mnemonics[format]
+	}
+	suffix, name := cop1FmtSuffixes[format], cop1ArithMnemonics[funct]
+	if suffix == "" || name == "" {
+		return ""
+	}
+	return name + "." + suffix
+}
@@ -0,0 +1,343 @@
+package mips
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/ChainSafe/vm-compat/asmparser"
+)
+
+// maxOperations bounds the operations tracked on a value, which also ends the walk around
+// loops that keep modifying it.
+const maxOperations = 8
+
+// location is where a value is held while walking back the instructions.
+type location struct {
+	register int64 // Register holding the value, unless stack is set.
+	stack    bool  // The value is spilled to the stack at offset from sp.
+	offset   int64
+}
+
+// operationKind is an operation applied to a value by an instruction.
+type operationKind uint8
+
+const (
+	operationAdd operationKind = iota + 1
+	operationOr
+	operationAnd
+	operationXor
+	operationShift
+)
+
+type operation struct {
+	kind    operationKind
+	operand int64
+}
+
+// query is a value resolved by walking back the instructions: where it is held, and the operations
+// applied to it after that point, last one first. It is comparable, to detect visited states.
+type query struct {
+	loc location
+	ops [maxOperations]operation
+	n   int
+}
+
+// push records an operation applied to the value, and reports false when too many are tracked.
+func (q *query) push(kind operationKind, operand int64) bool {
+	if q.n == maxOperations {
+		return false
+	}
+	q.ops[q.n] = operation{kind: kind, operand: operand}
+	q.n++
+	return true
+}
+
+// apply returns the value the query resolves to when its location holds value.
+func (q *query) apply(value int64) int64 {
+	for k := q.n - 1; k >= 0; k-- {
+		op := q.ops[k]
+		switch op.kind {
+		case operationAdd:
+			value += op.operand
+		case operationOr:
+			value |= op.operand
+		case operationAnd:
+			value &= op.operand
+		case operationXor:
+			value ^= op.operand
+		case operationShift:
+			value <<= op.operand
+		}
+	}
+	return value
+}
+
+// outcome is the result of interpreting an instruction backwards.
+type outcome int
+
+const (
+	pending  outcome = iota // The value is held in the location of the query.
+	resolved                // The value is known.
+	dynamic                 // The value is computed at run time.
+)
+
+// origin is where a value resolved by walking back the instructions was set: the constant set
+// by instr, or the reason the value could not be determined there.
+type origin struct {
+	value  int64
+	reason asmparser.UnresolvedReason // Set when the value could not be determined.
+	seg    *segment
+	instr  *instruction
+}
+
+// resolveState identifies a visited step of the value resolution.
+type resolveState struct {
+	seg *segment
+	idx int
+	q   query
+}
+
+// RetrieveSyscallNum returns the possible numbers of the syscall held in $v0, by interpreting
+// the preceding instructions backwards. Immediate loads, lui and ori pairs, register moves,
+// stack spills and reloads and the delay slots of calls are followed, along the basic blocks
+// of the segment and back into its callers. Every path yields a value, or the reason it
+// could not be determined when the number is computed at run time.
+func (g *callGraph) RetrieveSyscallNum(
+	seg asmparser.Segment,
+	instr asmparser.Instruction,
+) ([]*asmparser.Syscall, []*asmparser.UnresolvedSyscall, error) {
+	ins, ok := instr.(*instruction)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid instruction type: expected MIPS instruction, got %T", instr)
+	}
+	s, ok := seg.(*segment)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid segment type: expected MIPS segment, got %T", seg)
+	}
+	idx := s.indexOf(ins)
+	if idx < 0 {
+		return nil, nil, fmt.Errorf("instruction %s not found in segment %s", ins.Address(), s.label)
+	}
+	seen := make(map[resolveState]bool)
+	origins := g.resolveBefore(s, idx, query{loc: location{register: registerV0}}, seen)
+
+	type key struct {
+		number int
+		reason asmparser.UnresolvedReason
+		instr  *instruction
+	}
+	unique := make(map[key]bool, len(origins))
+	syscalls := make([]*asmparser.Syscall, 0, len(origins))
+	unresolved := make([]*asmparser.UnresolvedSyscall, 0)
+	for _, origin := range origins {
+		k := key{number: int(origin.value), reason: origin.reason, instr: origin.instr}
+		if unique[k] {
+			continue
+		}
+		unique[k] = true
+		if origin.reason != "" {
+			unresolved = append(unresolved, &asmparser.UnresolvedSyscall{Reason: origin.reason, Segment: origin.seg, Instruction: origin.instr})
+		} else {
+			syscalls = append(syscalls, &asmparser.Syscall{Number: k.number, Segment: origin.seg, Instruction: origin.instr})
+		}
+	}
+	return syscalls, unresolved, nil
+}
+
+// valuesOf returns the distinct values register may hold right before the instruction at idx,
+// along the paths where it could be determined.
+func (g *callGraph) valuesOf(seg *segment, idx int, register int64) []int64 {
+	values := make([]int64, 0)
+	for _, origin := range g.resolveBefore(seg, idx, query{loc: location{register: register}}, make(map[resolveState]bool)) {
+		if origin.reason == "" && !slices.Contains(values, origin.value) {
+			values = append(values, origin.value)
+		}
+	}
+	return values
+}
+
+// resolveBefore resolves q from the instructions that may execute right before the instruction
+// at idx: the end of the preceding blocks, and the call sites of the callers when idx may be
+// reached from the entry of the function.
+func (g *callGraph) resolveBefore(seg *segment, idx int, q query, seen map[resolveState]bool) []origin {
+	state := resolveState{seg: seg, idx: idx, q: q}
+	if seen[state] {
+		return nil
+	}
+	seen[state] = true
+
+	preds, entry := seg.predecessors(idx)
+	result := make([]origin, 0)
+	for _, pred := range preds {
+		result = append(result, g.resolve(seg, pred, q, seen)...)
+	}
+	if !entry {
+		return result
+	}
+	// The value comes from the callers, the delay slot of the call executes before the callee
+	sites := 0
+	for _, parent := range g.ParentsOf(seg) {
+		caller := parent.(*segment) //nolint:forcetypeassert
+		for _, site := range g.CallSites(caller, seg) {
+			sites++
+			result = append(result, g.resolveBefore(caller, caller.indexOf(site.(*instruction)), q, seen)...) //nolint:forcetypeassert
+		}
+	}
+	if sites == 0 {
+		result = append(result, origin{reason: asmparser.UnresolvedNoCaller, seg: seg, instr: seg.instructions[0]})
+	}
+	return result
+}
+
+// resolve walks back from the instruction at idx to the start of its basic block, in execution
+// order, until the value of q is known, and continues before the block otherwise.
+func (g *callGraph) resolve(seg *segment, idx int, q query, seen map[resolveState]bool) []origin {
+	start := seg.blockOf(idx).start
+	for pos := seg.position[idx]; pos >= start; pos-- {
+		instr := seg.instructions[seg.order[pos]]
+		next, value, out := interpret(instr, q)
+		switch out {
+		case resolved:
+			return []origin{{value: value, seg: seg, instr: instr}}
+		case dynamic:
+			return []origin{{reason: unresolvedReason(instr, q), seg: seg, instr: instr}}
+		}
+		q = next
+	}
+	return g.resolveBefore(seg, seg.order[start], q, seen)
+}
+
+// interpret executes the instruction backwards for the value of q: it returns where the value
+// was held before the instruction, or the value when the instruction sets it to a constant.
+func interpret(instr *instruction, q query) (query, int64, outcome) {
+	out := pending
+	if q.loc.stack {
+		q, out = interpretStack(instr, q)
+	} else {
+		q, out = interpretRegister(instr, q)
+	}
+	if out == pending && !q.loc.stack && q.loc.register == registerZero {
+		return q, q.apply(0), resolved
+	}
+	return q, 0, out
+}
+
+// unresolvedReason tells why the value of q is computed at run time by the instruction.
+func unresolvedReason(instr *instruction, q query) asmparser.UnresolvedReason {
+	switch {
+	case q.loc.stack:
+		return asmparser.UnresolvedStack
+	case instr.isCall():
+		return asmparser.UnresolvedCall
+	}
+	return asmparser.UnresolvedDynamic
+}
+
+// interpretStack follows a value spilled to the stack back to the register stored in its slot.
+func interpretStack(instr *instruction, q query) (query, outcome) {
+	switch {
+	case instr.isStore() && instr.operands[0] == registerSP && instr.operands[2] == q.loc.offset:
+		q.loc = location{register: instr.operands[1]}
+	case instr.isImmediateAdd() && instr.operands[0] == registerSP && instr.operands[1] == registerSP:
+		// Stack frame allocation, the slot is relative to the caller's sp
+		q.loc.offset += instr.operands[2]
+	default:
+		if dest, ok := instr.destination(); ok && dest == registerSP {
+			return q, dynamic
+		}
+	}
+	return q, pending
+}
+
+// interpretRegister follows a value held in a register back to the operands of the instruction
+// that wrote it, recording the operation applied by the instruction.
+//
+//nolint:cyclop
+func interpretRegister(instr *instruction, q query) (query, outcome) {
+	if instr.isCall() { // Calls clobber every register
+		return q, dynamic
+	}
+	dest, ok := instr.destination()
+	if !ok || dest != q.loc.register {
+		return q, pending
+	}
+	rs, rt := instr.operands[0], instr.operands[1]
+	// from continues with the value of register, before the operation of the instruction
+	from := func(register int64, kind operationKind, operand int64) (query, outcome) {
+		if !q.push(kind, operand) {
+			return q, dynamic
+		}
+		q.loc.register = register
+		return q, pending
+	}
+	switch {
+	case instr.opcode == 0x0f: // lui
+		if !q.push(operationAdd, int64(int32(instr.operands[2]<<16))) { //nolint:gosec
+			return q, dynamic
+		}
+		q.loc.register = registerZero
+		return q, pending
+	case instr.isImmediateAdd():
+		return from(rs, operationAdd, instr.operands[2])
+	case instr.opcode == 0x0c: // andi
+		return from(rs, operationAnd, instr.operands[2]&0xffff)
+	case instr.opcode == 0x0d: // ori
+		return from(rs, operationOr, instr.operands[2]&0xffff)
+	case instr.opcode == 0x0e: // xori
+		return from(rs, operationXor, instr.operands[2]&0xffff)
+	case instr.isLoad() && rs == registerSP:
+		q.loc = location{stack: true, offset: instr.operands[2]}
+		return q, pending
+	case instr.opcode == 0x00:
+		switch funct := instr.operands[4]; {
+		case funct == 0x20 || funct == 0x21 || funct == 0x25 || funct == 0x2c || funct == 0x2d:
+			// add, addu, or, dadd and daddu are moves when one operand is $zero
+			switch {
+			case rt == registerZero:
+				q.loc.register = rs
+			case rs == registerZero:
+				q.loc.register = rt
+			default:
+				return q, dynamic
+			}
+			return q, pending
+		case funct == 0x00 || funct == 0x38: // sll, dsll
+			return from(rt, operationShift, instr.operands[3])
+		case funct == 0x3c: // dsll32
+			return from(rt, operationShift, instr.operands[3]+32)
+		}
+	}
+	return q, dynamic
+}
+
+// isCall checks if the instruction is a call: jal, jalr, or a linking REGIMM branch such as bal.
+func (i *instruction) isCall() bool {
+	switch i.opcode {
+	case 0x03:
+		return true
+	case 0x00:
+		return i.operands[4] == 0x09
+	case 0x01:
+		return i.fields[fieldRT] >= 0x10 && i.fields[fieldRT] <= 0x13
+	}
+	return false
+}
+
+// isImmediateAdd checks if the instruction is addi, addiu, daddi or daddiu.
+func (i *instruction) isImmediateAdd() bool {
+	switch i.opcode {
+	case 0x08, 0x09, 0x18, 0x19:
+		return true
+	}
+	return false
+}
+
+// isLoad checks if the instruction loads a word or a doubleword: lw, lwu or ld.
+func (i *instruction) isLoad() bool {
+	return i.opcode == 0x23 || i.opcode == 0x27 || i.opcode == 0x37
+}
+
+// isStore checks if the instruction stores a word or a doubleword: sw or sd.
+func (i *instruction) isStore() bool {
+	return i.opcode == 0x2b || i.opcode == 0x3f
+}
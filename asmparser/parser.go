@@ -1,9 +1,11 @@
 // Package asmparser provides interfaces and structures for parsing and analyzing assembly code.
 package asmparser
 
-// Parser defines an interface for parsing assembly code from a given file path.
+import "io"
+
+// Parser defines an interface for parsing assembly code read from a disassembler output.
 type Parser interface {
-	Parse(path string) (CallGraph, error)
+	Parse(r io.Reader) (CallGraph, error)
 }
 
 // InstructionType represents different categories of MIPS instructions.
@@ -24,6 +26,20 @@ type Instruction interface {
 	Mnemonic() string      // Mnemonic returns the assembly mnemonic representation.
 	IsSyscall() bool       // IsSyscall returns true if the instruction is a syscall.
 	Line() int             // Line number of the instruction
+	// Source returns the Go source position the instruction was generated from, or nil if unknown.
+	Source() *SourcePosition
+	// Fields returns the sub-fields that select the operation beside opcode and funct, keyed by name
+	// (e.g. "rt" of a MIPS REGIMM branch or "fmt" of a COP1 instruction), or nil if there are none.
+	Fields() map[string]string
+	// BaseMnemonic returns the mnemonic of the operation selected by the encoding, without
+	// pseudo instruction aliases (e.g. "sll" rather than "nop").
+	BaseMnemonic() string
+}
+
+// SourcePosition represents a location in Go source code.
+type SourcePosition struct {
+	File string // File path, absolute when the disassembly provides it.
+	Line int    // Line number in the file.
 }
 
 // Segment defines an interface representing a block of assembly instructions.
@@ -34,16 +50,93 @@ type Segment interface {
 	Label() string
 	// Instructions return the list of instructions in the segment.
 	Instructions() []Instruction
+	// Blocks returns the basic blocks of the segment in address order, the first one is the entry.
+	Blocks() []BasicBlock
+}
+
+// BasicBlock defines an interface representing a straight-line run of instructions of a segment,
+// entered only at its first instruction and left only after its last one.
+// On MIPS, a block ending with a jump or branch includes its delay slot, except for a branch likely,
+// whose delay slot is annulled when the branch is not taken and is a block of its own.
+type BasicBlock interface {
+	// Address returns the address of the first instruction of the block.
+	Address() string
+	// Instructions returns the instructions of the block in address order.
+	Instructions() []Instruction
+	// Successors returns the blocks of the same segment control may continue with after the block.
+	// Calls return to the next block and are not edges to the callee.
+	Successors() []BasicBlock
+	// Predecessors returns the blocks of the same segment control may come from into the block.
+	Predecessors() []BasicBlock
 }
 
 // CallGraph defines an interface representing a call graph of segments.
 type CallGraph interface {
-	// Segments returns all segments in the call graph.
+	// Segments returns all segments in the call graph, in address order.
 	Segments() []Segment
-	// ParentsOf returns the parent segments of a given segment.
+	// ParentsOf returns the parent segments of a given segment, in address order.
 	ParentsOf(segment Segment) []Segment
-	// RetrieveSyscallNum returns the number of the syscall from the instr
-	RetrieveSyscallNum(segment Segment, instr Instruction) ([]*Syscall, error)
+	// CallSites returns the instructions of caller that transfer control to callee, in address order.
+	CallSites(caller, callee Segment) []Instruction
+	// RetrieveSyscallNum returns the numbers of the syscall from the instr along every path reaching it,
+	// and the paths along which the number could not be determined.
+	RetrieveSyscallNum(segment Segment, instr Instruction) ([]*Syscall, []*UnresolvedSyscall, error)
+	// IndirectCalls returns the calls and jumps through a register, with the targets they were resolved to.
+	IndirectCalls() []*IndirectCall
+}
+
+// IndirectKind classifies an indirect call by how its target address was loaded.
+type IndirectKind string
+
+const (
+	IndirectItab    IndirectKind = "itab"    // Interface method call, loaded from the fun table of an itab.
+	IndirectClosure IndirectKind = "closure" // Func value call, loaded from the funcval in the context register.
+	// IndirectJumpTable is a switch dispatch or a call through a table of functions, whose entry is
+	// loaded from a table in the data sections at an index computed at run time.
+	IndirectJumpTable IndirectKind = "jumptable"
+	IndirectUnknown   IndirectKind = "unknown" // The origin of the target address was not recognized.
+)
+
+// IndirectCall is a call or jump through a register, whose targets are not encoded in the instruction.
+type IndirectCall struct {
+	Segment     Segment
+	Instruction Instruction
+	Kind        IndirectKind
+	Targets     []Segment // Resolved targets, empty when the call could not be resolved.
+	// Blocks are the resolved targets within Segment of a jump through a jump table, which are
+	// also successors of the block of the jump.
+	Blocks []BasicBlock
+}
+
+// Resolved reports whether targets were found for the indirect call.
+func (c *IndirectCall) Resolved() bool {
+	return len(c.Targets) > 0 || len(c.Blocks) > 0
+}
+
+// Config holds the settings of a Parser.
+type Config struct {
+	// Binary is the ELF binary the disassembly was generated from. Its data sections provide the
+	// targets of indirect calls when parsing disassembler output; ELF input is used directly.
+	Binary []byte
+}
+
+// Option configures a Parser.
+type Option func(*Config)
+
+// WithBinary sets the ELF binary the parsed disassembly was generated from.
+func WithBinary(binary []byte) Option {
+	return func(c *Config) {
+		c.Binary = binary
+	}
+}
+
+// NewConfig applies the options to an empty Config.
+func NewConfig(opts ...Option) *Config {
+	config := &Config{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
 }
 
 // Syscall holds syscall origin related details
@@ -52,3 +145,21 @@ type Syscall struct {
 	Segment     Segment
 	Instruction Instruction
 }
+
+// UnresolvedReason tells why the number of a syscall could not be determined along a path.
+type UnresolvedReason string
+
+const (
+	UnresolvedDynamic  UnresolvedReason = "computed at run time"              // Set by an instruction that is not interpreted, such as a load.
+	UnresolvedCall     UnresolvedReason = "clobbered by a call"               // Held in a register across a call.
+	UnresolvedStack    UnresolvedReason = "stack pointer changed at run time" // Spilled to a stack slot that cannot be followed.
+	UnresolvedNoCaller UnresolvedReason = "no known caller"                   // Held on entry of a function that has no known call site.
+)
+
+// UnresolvedSyscall is a path along which the number of a syscall could not be determined.
+type UnresolvedSyscall struct {
+	Reason  UnresolvedReason
+	Segment Segment // Function where the number was lost.
+	// Instruction set the number, or is the first instruction of the function without callers.
+	Instruction Instruction
+}
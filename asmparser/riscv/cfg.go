@@ -0,0 +1,169 @@
+package riscv
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/ChainSafe/vm-compat/asmparser"
+)
+
+// block represents a basic block of a segment implementing the asmparser.BasicBlock interface.
+type block struct {
+	seg          *segment
+	start, end   int // Range of the block in the instructions of the segment.
+	successors   []*block
+	predecessors []*block
+}
+
+func (b *block) Address() string {
+	return b.seg.instructions[b.start].Address()
+}
+
+func (b *block) Instructions() []asmparser.Instruction {
+	instrs := make([]asmparser.Instruction, 0, b.end-b.start)
+	for _, ins := range b.seg.instructions[b.start:b.end] {
+		instrs = append(instrs, ins)
+	}
+	return instrs
+}
+
+func (b *block) Successors() []asmparser.BasicBlock {
+	return basicBlocks(b.successors)
+}
+
+func (b *block) Predecessors() []asmparser.BasicBlock {
+	return basicBlocks(b.predecessors)
+}
+
+func basicBlocks(blocks []*block) []asmparser.BasicBlock {
+	result := make([]asmparser.BasicBlock, len(blocks))
+	for i, b := range blocks {
+		result[i] = b
+	}
+	return result
+}
+
+// branch describes how a jump or branch instruction transfers control within its function.
+type branch struct {
+	target    uint64 // Destination, valid when hasTarget is set.
+	hasTarget bool
+	next      bool // Control may continue with the next instruction when the branch is not taken.
+}
+
+// branchOf returns the control transfer of the instruction, or false if control always continues
+// with the next instruction. Calls, which link the return address, are not transfers.
+func (i *instruction) branchOf() (branch, bool) {
+	switch {
+	case i.op == opBranch:
+		return branch{target: i.address + uint64(i.imm), hasTarget: true, next: true}, true //nolint:gosec
+	case i.op == opJAL && i.rd == registerZero: // j
+		return branch{target: i.target, hasTarget: true}, true
+	case i.op == opJALR && i.rd == registerZero: // ret, tail calls and jumps through a register
+		return branch{target: i.target, hasTarget: i.hasTarget}, true
+	}
+	return branch{}, false
+}
+
+// buildBlocks splits the instructions of the segment into basic blocks. A block ends after
+// a jump or branch, and a new one starts at every target of a branch or jump table.
+func (s *segment) buildBlocks() {
+	s.blocks = make([]*block, 0)
+	if len(s.instructions) == 0 {
+		return
+	}
+	leaders := map[int]bool{0: true}
+	for idx, instr := range s.instructions {
+		br, ok := instr.branchOf()
+		if !ok {
+			continue
+		}
+		leaders[idx+1] = true
+		if target, ok := s.indexAt(br.target); ok && br.hasTarget {
+			leaders[target] = true
+		}
+		for _, addr := range s.tables[idx] {
+			if target, ok := s.indexAt(addr); ok {
+				leaders[target] = true
+			}
+		}
+	}
+	starts := make([]int, 0, len(leaders))
+	for start := range leaders {
+		if start < len(s.instructions) {
+			starts = append(starts, start)
+		}
+	}
+	sort.Ints(starts)
+	for k, start := range starts {
+		end := len(s.instructions)
+		if k+1 < len(starts) {
+			end = starts[k+1]
+		}
+		s.blocks = append(s.blocks, &block{seg: s, start: start, end: end})
+	}
+	for k, b := range s.blocks {
+		for _, succ := range s.successorsOf(k) {
+			b.successors = append(b.successors, succ)
+			succ.predecessors = append(succ.predecessors, b)
+		}
+	}
+}
+
+// successorsOf returns the blocks control may continue with after the k-th block.
+func (s *segment) successorsOf(k int) []*block {
+	b := s.blocks[k]
+	var next *block
+	if k+1 < len(s.blocks) {
+		next = s.blocks[k+1]
+	}
+	br, ok := s.instructions[b.end-1].branchOf()
+	if !ok {
+		if next == nil {
+			return nil
+		}
+		return []*block{next}
+	}
+	successors := make([]*block, 0, 2)
+	if target, ok := s.indexAt(br.target); ok && br.hasTarget {
+		successors = append(successors, s.blockOf(target))
+	}
+	for _, addr := range s.tables[b.end-1] {
+		if target, ok := s.indexAt(addr); ok && !slices.Contains(successors, s.blockOf(target)) {
+			successors = append(successors, s.blockOf(target))
+		}
+	}
+	if br.next && next != nil && !slices.Contains(successors, next) {
+		successors = append(successors, next)
+	}
+	return successors
+}
+
+// blockOf returns the block holding the instruction at idx.
+func (s *segment) blockOf(idx int) *block {
+	k := sort.Search(len(s.blocks), func(k int) bool {
+		return s.blocks[k].end > idx
+	})
+	return s.blocks[k]
+}
+
+// indexAt returns the index of the instruction at addr, if the segment holds it.
+func (s *segment) indexAt(addr uint64) (int, bool) {
+	idx := sort.Search(len(s.instructions), func(i int) bool {
+		return s.instructions[i].address >= addr
+	})
+	return idx, idx < len(s.instructions) && s.instructions[idx].address == addr
+}
+
+// predecessors returns the indexes of the instructions control may come from into the instruction
+// at idx, and whether the instruction may be reached from the entry of the segment.
+func (s *segment) predecessors(idx int) ([]int, bool) {
+	b := s.blockOf(idx)
+	if idx > b.start {
+		return []int{idx - 1}, false
+	}
+	preds := make([]int, 0, len(b.predecessors))
+	for _, pred := range b.predecessors {
+		preds = append(preds, pred.end-1)
+	}
+	return preds, b.start == 0
+}
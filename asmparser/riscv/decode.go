@@ -0,0 +1,202 @@
+package riscv
+
+// Major opcodes of the RV64 base instructions.
+const (
+	opLoad    = 0x03
+	opLoadFP  = 0x07
+	opMiscMem = 0x0f
+	opImm     = 0x13
+	opAUIPC   = 0x17
+	opImm32   = 0x1b
+	opStore   = 0x23
+	opStoreFP = 0x27
+	opAMO     = 0x2f
+	opReg     = 0x33
+	opLUI     = 0x37
+	opReg32   = 0x3b
+	opMAdd    = 0x43
+	opMSub    = 0x47
+	opNMSub   = 0x4b
+	opNMAdd   = 0x4f
+	opFP      = 0x53
+	opBranch  = 0x63
+	opJALR    = 0x67
+	opJAL     = 0x6f
+	opSystem  = 0x73
+)
+
+// isCompressed reports whether the low half-word of an instruction is a 16-bit compressed
+// instruction. 32-bit instructions always have their two lowest bits set.
+func isCompressed(low uint16) bool {
+	return low&0x3 != 0x3
+}
+
+// decodeWord decodes a 32-bit RV64 instruction.
+// https://github.com/riscv/riscv-isa-manual (Base Instruction Formats)
+func decodeWord(word uint32) *instruction {
+	instr := &instruction{
+		raw:    word,
+		op:     word & 0x7f,
+		rd:     (word >> 7) & 0x1f,
+		funct3: (word >> 12) & 0x7,
+		rs1:    (word >> 15) & 0x1f,
+		rs2:    (word >> 20) & 0x1f,
+		funct7: word >> 25,
+	}
+	switch instr.op {
+	case opLoad, opLoadFP, opMiscMem, opImm, opImm32, opJALR, opSystem:
+		instr.imm = int64(int32(word) >> 20)
+	case opStore, opStoreFP:
+		instr.imm = int64(int32(word)>>25<<5) | int64((word>>7)&0x1f)
+	case opBranch:
+		instr.imm = int64(int32(word)>>31<<12) |
+			int64((word>>7)&0x1)<<11 |
+			int64((word>>25)&0x3f)<<5 |
+			int64((word>>8)&0xf)<<1
+	case opLUI, opAUIPC:
+		instr.imm = int64(int32(word & 0xfffff000))
+	case opJAL:
+		instr.imm = int64(int32(word)>>31<<20) |
+			int64((word>>12)&0xff)<<12 |
+			int64((word>>20)&0x1)<<11 |
+			int64((word>>21)&0x3ff)<<1
+	}
+	instr.mnemonic = mnemonicOf(instr)
+	return instr
+}
+
+// decodeCompressed decodes a 16-bit RVC instruction into its equivalent base instruction.
+// The raw encoding is kept so the instruction is still reported as compressed.
+//
+//nolint:cyclop,funlen
+func decodeCompressed(half uint16) *instruction {
+	h := uint32(half)
+	bit := func(pos uint) uint32 { return (h >> pos) & 0x1 }
+	bits := func(hi, lo uint) uint32 { return (h >> lo) & (1<<(hi-lo+1) - 1) }
+	// sext sign extends the low n bits of v
+	sext := func(v uint32, n uint) int64 { return int64(int32(v<<(32-n)) >> (32 - n)) }
+
+	instr := &instruction{raw: h, compressed: true}
+	rdFull := bits(11, 7)
+	rs2Full := bits(6, 2)
+	rdPrime := bits(4, 2) + 8  // rd' and rs2' of the CIW, CL, CS and CA formats
+	rs1Prime := bits(9, 7) + 8 // rs1' and rd' of the CL, CS, CA and CB formats
+	imm6 := sext(bit(12)<<5|bits(6, 2), 6)
+
+	// set fills the expanded base instruction.
+	set := func(name string, op, funct3, rd, rs1, rs2 uint32, imm int64) *instruction {
+		instr.mnemonic = name
+		instr.op, instr.funct3, instr.rd, instr.rs1, instr.rs2, instr.imm = op, funct3, rd, rs1, rs2, imm
+		return instr
+	}
+	unknown := func() *instruction {
+		instr.mnemonic = "unknown"
+		return instr
+	}
+
+	switch h&0x3<<3 | bits(15, 13) {
+	// Quadrant 0
+	case 0x00:
+		imm := bits(12, 11)<<4 | bits(10, 7)<<6 | bit(6)<<2 | bit(5)<<3
+		if imm == 0 {
+			return unknown()
+		}
+		return set("c.addi4spn", opImm, 0x0, rdPrime, registerSP, 0, int64(imm))
+	case 0x01:
+		return set("c.fld", opLoadFP, 0x3, rdPrime, rs1Prime, 0, int64(bits(12, 10)<<3|bits(6, 5)<<6))
+	case 0x02:
+		return set("c.lw", opLoad, 0x2, rdPrime, rs1Prime, 0, int64(bits(12, 10)<<3|bit(6)<<2|bit(5)<<6))
+	case 0x03:
+		return set("c.ld", opLoad, 0x3, rdPrime, rs1Prime, 0, int64(bits(12, 10)<<3|bits(6, 5)<<6))
+	case 0x05:
+		return set("c.fsd", opStoreFP, 0x3, 0, rs1Prime, rdPrime, int64(bits(12, 10)<<3|bits(6, 5)<<6))
+	case 0x06:
+		return set("c.sw", opStore, 0x2, 0, rs1Prime, rdPrime, int64(bits(12, 10)<<3|bit(6)<<2|bit(5)<<6))
+	case 0x07:
+		return set("c.sd", opStore, 0x3, 0, rs1Prime, rdPrime, int64(bits(12, 10)<<3|bits(6, 5)<<6))
+
+	// Quadrant 1
+	case 0x08:
+		if rdFull == registerZero {
+			return set("c.nop", opImm, 0x0, 0, 0, 0, 0)
+		}
+		return set("c.addi", opImm, 0x0, rdFull, rdFull, 0, imm6)
+	case 0x09:
+		return set("c.addiw", opImm32, 0x0, rdFull, rdFull, 0, imm6)
+	case 0x0a:
+		return set("c.li", opImm, 0x0, rdFull, registerZero, 0, imm6)
+	case 0x0b:
+		if rdFull == registerSP {
+			imm := sext(bit(12)<<9|bit(6)<<4|bit(5)<<6|bits(4, 3)<<7|bit(2)<<5, 10)
+			return set("c.addi16sp", opImm, 0x0, registerSP, registerSP, 0, imm)
+		}
+		return set("c.lui", opLUI, 0x0, rdFull, 0, 0, imm6<<12)
+	case 0x0c:
+		shamt := int64(bit(12)<<5 | bits(6, 2))
+		switch bits(11, 10) {
+		case 0x0:
+			return set("c.srli", opImm, 0x5, rs1Prime, rs1Prime, 0, shamt)
+		case 0x1:
+			instr.funct7 = 0x20
+			return set("c.srai", opImm, 0x5, rs1Prime, rs1Prime, 0, shamt|0x400)
+		case 0x2:
+			return set("c.andi", opImm, 0x7, rs1Prime, rs1Prime, 0, imm6)
+		}
+		switch bit(12)<<2 | bits(6, 5) {
+		case 0x0:
+			instr.funct7 = 0x20
+			return set("c.sub", opReg, 0x0, rs1Prime, rs1Prime, rdPrime, 0)
+		case 0x1:
+			return set("c.xor", opReg, 0x4, rs1Prime, rs1Prime, rdPrime, 0)
+		case 0x2:
+			return set("c.or", opReg, 0x6, rs1Prime, rs1Prime, rdPrime, 0)
+		case 0x3:
+			return set("c.and", opReg, 0x7, rs1Prime, rs1Prime, rdPrime, 0)
+		case 0x4:
+			instr.funct7 = 0x20
+			return set("c.subw", opReg32, 0x0, rs1Prime, rs1Prime, rdPrime, 0)
+		case 0x5:
+			return set("c.addw", opReg32, 0x0, rs1Prime, rs1Prime, rdPrime, 0)
+		}
+		return unknown()
+	case 0x0d:
+		imm := sext(bit(12)<<11|bit(11)<<4|bits(10, 9)<<8|bit(8)<<10|bit(7)<<6|bit(6)<<7|bits(5, 3)<<1|bit(2)<<5, 12)
+		return set("c.j", opJAL, 0x0, registerZero, 0, 0, imm)
+	case 0x0e, 0x0f:
+		imm := sext(bit(12)<<8|bits(11, 10)<<3|bits(6, 5)<<6|bits(4, 3)<<1|bit(2)<<5, 9)
+		if bits(15, 13) == 0x6 {
+			return set("c.beqz", opBranch, 0x0, 0, rs1Prime, registerZero, imm)
+		}
+		return set("c.bnez", opBranch, 0x1, 0, rs1Prime, registerZero, imm)
+
+	// Quadrant 2
+	case 0x10:
+		return set("c.slli", opImm, 0x1, rdFull, rdFull, 0, int64(bit(12)<<5|bits(6, 2)))
+	case 0x11:
+		return set("c.fldsp", opLoadFP, 0x3, rdFull, registerSP, 0, int64(bit(12)<<5|bits(6, 5)<<3|bits(4, 2)<<6))
+	case 0x12:
+		return set("c.lwsp", opLoad, 0x2, rdFull, registerSP, 0, int64(bit(12)<<5|bits(6, 4)<<2|bits(3, 2)<<6))
+	case 0x13:
+		return set("c.ldsp", opLoad, 0x3, rdFull, registerSP, 0, int64(bit(12)<<5|bits(6, 5)<<3|bits(4, 2)<<6))
+	case 0x14:
+		switch {
+		case bit(12) == 0 && rs2Full == registerZero:
+			return set("c.jr", opJALR, 0x0, registerZero, rdFull, 0, 0)
+		case bit(12) == 0:
+			return set("c.mv", opReg, 0x0, rdFull, registerZero, rs2Full, 0)
+		case rdFull == registerZero && rs2Full == registerZero:
+			return set("c.ebreak", opSystem, 0x0, 0, 0, 0, 1)
+		case rs2Full == registerZero:
+			return set("c.jalr", opJALR, 0x0, registerRA, rdFull, 0, 0)
+		default:
+			return set("c.add", opReg, 0x0, rdFull, rdFull, rs2Full, 0)
+		}
+	case 0x15:
+		return set("c.fsdsp", opStoreFP, 0x3, 0, registerSP, rs2Full, int64(bits(12, 10)<<3|bits(9, 7)<<6))
+	case 0x16:
+		return set("c.swsp", opStore, 0x2, 0, registerSP, rs2Full, int64(bits(12, 9)<<2|bits(8, 7)<<6))
+	case 0x17:
+		return set("c.sdsp", opStore, 0x3, 0, registerSP, rs2Full, int64(bits(12, 10)<<3|bits(9, 7)<<6))
+	}
+	return unknown()
+}
@@ -0,0 +1,57 @@
+package riscv
+
+import (
+	"debug/elf"
+	"fmt"
+	"io"
+
+	"github.com/ChainSafe/vm-compat/asmparser/elftext"
+)
+
+// parseELF decodes the .text section of a RISC-V 64 ELF binary into a CallGraph.
+// Segments are derived from the function symbols of the symbol table, the
+// same way llvm-objdump labels its output.
+func parseELF(r io.ReaderAt) (*callGraph, error) {
+	file, err := elf.NewFile(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading elf file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	if file.Machine != elf.EM_RISCV || file.Class != elf.ELFCLASS64 {
+		return nil, fmt.Errorf("unsupported elf machine: %s %s", file.Machine, file.Class)
+	}
+	text, err := elftext.Read(file)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := newGraphBuilder()
+	lineNum := 0
+	for i, sym := range text.Functions {
+		end := text.End(i)
+		lineNum++
+		builder.startSegment(sym.Value, sym.Name)
+		for pc := sym.Value; pc+2 <= end; {
+			offset := pc - text.Addr
+			var instr *instruction
+			if low := file.ByteOrder.Uint16(text.Code[offset : offset+2]); isCompressed(low) {
+				instr = decodeCompressed(low)
+			} else if pc+4 <= end {
+				instr = decodeWord(file.ByteOrder.Uint32(text.Code[offset : offset+4]))
+			} else {
+				break
+			}
+			instr.address = pc
+			lineNum++
+			instr.line = lineNum
+			if err = builder.add(instr); err != nil {
+				return nil, err
+			}
+			pc += instr.size()
+		}
+	}
+	return builder.finish(), nil
+}
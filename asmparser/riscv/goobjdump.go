@@ -0,0 +1,101 @@
+package riscv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/ChainSafe/vm-compat/asmparser"
+)
+
+var (
+	// Regular expressions for parsing the output of `go tool objdump`.
+	goSymbolRegex      = regexp.MustCompile(`^TEXT (.+)\(SB\)(?:\s+(.*))?$`)
+	goInstructionRegex = regexp.MustCompile(`^\s+(\S*):(-?\d+)\s+0x([0-9a-fA-F]+)\s+([0-9a-fA-F]{8}|[0-9a-fA-F]{4})\s+(.*)$`)
+)
+
+// isGoObjdump reports whether the buffered input looks like `go tool objdump` output.
+func isGoObjdump(r *bufio.Reader) bool {
+	head, _ := r.Peek(512)
+	return bytes.HasPrefix(bytes.TrimLeft(head, " \t\r\n"), []byte("TEXT "))
+}
+
+// parseGoObjdump parses `go tool objdump` output into a CallGraph.
+// The tool prints 32-bit instructions as words and compressed instructions as bytes in memory order.
+// Only the base name of source files is printed per instruction. It is resolved to the full path
+// on the TEXT line of the function when the names match, as the instructions of a function
+// mostly come from its own file. The instructions of inlined functions are resolved against the
+// full paths listed on the other TEXT lines, when only one of them has that base name.
+func parseGoObjdump(r io.Reader) (*callGraph, error) {
+	builder := newGraphBuilder()
+	files := make(map[string][]string) // base name -> full paths
+	inlined := make([]*asmparser.SourcePosition, 0)
+	label, textFile := "", ""
+	pending := false // the segment starts at the next instruction
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNum++
+		if matches := goSymbolRegex.FindStringSubmatch(line); matches != nil {
+			label, textFile, pending = matches[1], matches[2], true
+			if base := filepath.Base(textFile); textFile != "" && !slices.Contains(files[base], textFile) {
+				files[base] = append(files[base], textFile)
+			}
+			continue
+		}
+		matches := goInstructionRegex.FindStringSubmatch(line)
+		if matches == nil { // Ignore empty and unrecognized lines
+			continue
+		}
+		pcAddress, err := strconv.ParseUint(matches[3], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid instruction address: %w", err)
+		}
+		code, err := hex.DecodeString(matches[4])
+		if err != nil {
+			return nil, fmt.Errorf("invalid RISC-V instruction format: %w", err)
+		}
+		var instr *instruction
+		if len(code) == 4 {
+			instr = decodeWord(binary.BigEndian.Uint32(code))
+		} else {
+			instr = decodeCompressed(binary.LittleEndian.Uint16(code))
+		}
+		instr.address = pcAddress
+		instr.line = lineNum
+		if srcLine, err := strconv.Atoi(matches[2]); err == nil && matches[1] != "" && srcLine > 0 {
+			instr.source = &asmparser.SourcePosition{File: matches[1], Line: srcLine}
+			if textFile != "" && filepath.Base(textFile) == matches[1] {
+				instr.source.File = textFile
+			} else {
+				inlined = append(inlined, instr.source)
+			}
+		}
+
+		// The segment address is only known once its first instruction is read.
+		if pending {
+			builder.startSegment(pcAddress, label)
+			pending = false
+		}
+		if err = builder.add(instr); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading disassembly: %w", err)
+	}
+	for _, source := range inlined {
+		if paths := files[source.File]; len(paths) == 1 {
+			source.File = paths[0]
+		}
+	}
+	return builder.finish(), nil
+}
@@ -0,0 +1,138 @@
+package riscv
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/ChainSafe/vm-compat/asmparser"
+	"github.com/ChainSafe/vm-compat/asmparser/indirect"
+)
+
+// resolveIndirect links the calls and jumps through a register to their candidate targets,
+// read from the data sections of binary. Without a binary every indirect call is unresolved.
+// The targets of a jump table within its function are successors of the block of the jump,
+// so the blocks of those functions are built again.
+func (g *callGraph) resolveIndirect(binary []byte) error {
+	segments := g.functions()
+	resolver := &indirect.Resolver{Functions: make([]*indirect.Function, 0, len(segments)), Context: registerCtxt}
+	for _, seg := range segments {
+		resolver.Functions = append(resolver.Functions, g.function(seg))
+	}
+	calls, err := resolver.Resolve(binary)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range calls {
+		seg := segments[c.Function]
+		instr := seg.instructions[c.Index]
+		call := &asmparser.IndirectCall{Segment: seg, Instruction: instr, Kind: c.Kind}
+		if len(c.Table) > 0 {
+			seg.tables[c.Index] = c.Table
+		}
+		resolved := make(map[uint64]bool, len(c.Targets))
+		for _, addr := range c.Targets {
+			if target, ok := g.segments[addr]; ok {
+				resolved[addr] = true
+				call.Targets = append(call.Targets, target)
+				target.parents[seg.address] = true
+			}
+		}
+		g.indirectTargets[instr] = resolved
+		g.indirect = append(g.indirect, call)
+	}
+
+	for _, seg := range segments {
+		if len(seg.tables) > 0 {
+			seg.buildBlocks()
+		}
+	}
+	for _, call := range g.indirect {
+		if call.Kind != asmparser.IndirectJumpTable {
+			continue
+		}
+		seg := call.Segment.(*segment)                                                  //nolint:forcetypeassert
+		for _, addr := range seg.tables[seg.indexOf(call.Instruction.(*instruction))] { //nolint:forcetypeassert
+			idx, _ := seg.indexAt(addr)
+			if b := seg.blockOf(idx); !slices.Contains(call.Blocks, asmparser.BasicBlock(b)) {
+				call.Blocks = append(call.Blocks, b)
+			}
+		}
+	}
+	return nil
+}
+
+// function returns the instructions of the segment in the form the resolution of indirect calls follows.
+func (g *callGraph) function(seg *segment) *indirect.Function {
+	fn := &indirect.Function{
+		Entry: seg.address,
+		Ops:   make([]indirect.Op, 0, len(seg.instructions)),
+		Values: func(idx int, register int) []int64 {
+			return g.valuesOf(seg, idx, uint32(register)) //nolint:gosec
+		},
+	}
+	for _, instr := range seg.instructions {
+		fn.Ops = append(fn.Ops, instr.indirectOp())
+	}
+	return fn
+}
+
+// functions returns the segments holding instructions, sorted by address.
+func (g *callGraph) functions() []*segment {
+	segments := make([]*segment, 0, len(g.segments))
+	for _, seg := range g.segments {
+		if len(seg.instructions) > 0 {
+			segments = append(segments, seg)
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].address < segments[j].address
+	})
+	return segments
+}
+
+// indirectOp decodes the instruction into the operations followed by the resolution of indirect calls.
+//
+//nolint:gosec
+func (i *instruction) indirectOp() indirect.Op {
+	op := indirect.Op{Addr: i.address, Kind: indirect.OpOther, Dest: indirect.NoRegister}
+	if dest, ok := i.destination(); ok {
+		op.Dest = int(dest)
+	}
+	switch {
+	case i.isIndirectJump():
+		op.Kind, op.Src[0] = indirect.OpJump, int(i.rs1)
+	case i.isLoad():
+		op.Kind, op.Src[0], op.Imm, op.Size = indirect.OpLoad, int(i.rs1), i.imm, 4
+		if i.funct3 == 0x3 { // ld
+			op.Size = 8
+		}
+	case i.isRegisterAdd():
+		op.Kind, op.Src = indirect.OpAdd, [2]int{int(i.rs1), int(i.rs2)}
+	case i.op == opImm && i.funct3 == 0x1: // slli
+		op.Kind, op.Src[0], op.Imm = indirect.OpShift, int(i.rs1), i.imm&0x3f
+	case i.op == opAUIPC:
+		op.Kind, op.Imm = indirect.OpUpper, int64(i.address)+i.imm
+	case i.op == opImm && i.funct3 == 0x0: // addi
+		op.Kind, op.Src[0], op.Imm = indirect.OpAddImm, int(i.rs1), i.imm
+	}
+	return op
+}
+
+// isRegisterAdd checks if the instruction is an add of two registers other than zero.
+func (i *instruction) isRegisterAdd() bool {
+	return i.op == opReg && i.funct3 == 0x0 && i.funct7 == 0x0 && i.rs1 != registerZero && i.rs2 != registerZero
+}
+
+// isLoad checks if the instruction loads a word or a doubleword: lw, ld or lwu.
+func (i *instruction) isLoad() bool {
+	return i.op == opLoad && (i.funct3 == 0x2 || i.funct3 == 0x3 || i.funct3 == 0x6)
+}
+
+// isIndirectJump checks if the instruction is a jalr with an unknown target, other than a return.
+func (i *instruction) isIndirectJump() bool {
+	if i.op != opJALR || i.hasTarget {
+		return false
+	}
+	return i.rd != registerZero || i.rs1 != registerRA
+}
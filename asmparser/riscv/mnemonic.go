@@ -0,0 +1,132 @@
+package riscv
+
+// Mnemonic tables used to name decoded instructions, indexed by funct3 unless stated otherwise.
+// Pseudo instructions are only rendered for the common cases emitted by the Go toolchain.
+var (
+	loadMnemonics   = [8]string{"lb", "lh", "lw", "ld", "lbu", "lhu", "lwu", ""}
+	storeMnemonics  = [8]string{"sb", "sh", "sw", "sd", "", "", "", ""}
+	branchMnemonics = [8]string{"beq", "bne", "", "", "blt", "bge", "bltu", "bgeu"}
+	immMnemonics    = [8]string{"addi", "slli", "slti", "sltiu", "xori", "srli", "ori", "andi"}
+	imm32Mnemonics  = [8]string{"addiw", "slliw", "", "", "", "srliw", "", ""}
+	regMnemonics    = [8]string{"add", "sll", "slt", "sltu", "xor", "srl", "or", "and"}
+	reg32Mnemonics  = [8]string{"addw", "sllw", "", "", "", "srlw", "", ""}
+	mulMnemonics    = [8]string{"mul", "mulh", "mulhsu", "mulhu", "div", "divu", "rem", "remu"}
+	mul32Mnemonics  = [8]string{"mulw", "", "", "", "divw", "divuw", "remw", "remuw"}
+	csrMnemonics    = [8]string{"", "csrrw", "csrrs", "csrrc", "", "csrrwi", "csrrsi", "csrrci"}
+
+	// amoMnemonics maps the funct5 field of atomic (A extension) instructions.
+	amoMnemonics = map[uint32]string{
+		0x00: "amoadd", 0x01: "amoswap", 0x02: "lr", 0x03: "sc", 0x04: "amoxor", 0x08: "amoor",
+		0x0c: "amoand", 0x10: "amomin", 0x14: "amomax", 0x18: "amominu", 0x1c: "amomaxu",
+	}
+)
+
+// mnemonicOf returns the assembly mnemonic of a decoded 32-bit instruction,
+// rendering the pseudo instructions commonly emitted by the Go toolchain.
+func mnemonicOf(i *instruction) string {
+	switch {
+	case i.op == opJAL && i.rd == registerZero:
+		return "j"
+	case i.op == opJALR && i.rd == registerZero && i.rs1 == registerRA && i.imm == 0:
+		return "ret"
+	case i.op == opImm && i.raw == 0x00000013:
+		return "nop"
+	case i.op == opImm && i.funct3 == 0x0 && i.rs1 == registerZero:
+		return "li"
+	case i.op == opImm && i.funct3 == 0x0 && i.imm == 0:
+		return "mv"
+	}
+	return baseMnemonicOf(i)
+}
+
+// baseMnemonicOf returns the mnemonic of the operation selected by the opcode and funct
+// fields of a decoded 32-bit instruction, without pseudo instruction aliases.
+//
+//nolint:cyclop
+func baseMnemonicOf(i *instruction) string {
+	var name string
+	switch i.op {
+	case opLUI:
+		name = "lui"
+	case opAUIPC:
+		name = "auipc"
+	case opJAL:
+		name = "jal"
+	case opJALR:
+		name = "jalr"
+	case opBranch:
+		name = branchMnemonics[i.funct3]
+	case opLoad:
+		name = loadMnemonics[i.funct3]
+	case opStore:
+		name = storeMnemonics[i.funct3]
+	case opImm:
+		if i.funct3 == 0x5 && i.funct7>>1 == 0x10 {
+			return "srai"
+		}
+		name = immMnemonics[i.funct3]
+	case opImm32:
+		if i.funct3 == 0x5 && i.funct7 == 0x20 {
+			return "sraiw"
+		}
+		name = imm32Mnemonics[i.funct3]
+	case opReg:
+		name = regMnemonic(i.funct7, i.funct3, regMnemonics, mulMnemonics, "sub", "sra")
+	case opReg32:
+		name = regMnemonic(i.funct7, i.funct3, reg32Mnemonics, mul32Mnemonics, "subw", "sraw")
+	case opAMO:
+		if name = amoMnemonics[i.funct7>>2]; name != "" {
+			switch i.funct3 {
+			case 0x2:
+				name += ".w"
+			case 0x3:
+				name += ".d"
+			default:
+				name = ""
+			}
+		}
+	case opMiscMem:
+		name = map[uint32]string{0x0: "fence", 0x1: "fence.i"}[i.funct3]
+	case opSystem:
+		switch {
+		case i.raw == 0x00000073:
+			return "ecall"
+		case i.raw == 0x00100073:
+			return "ebreak"
+		}
+		name = csrMnemonics[i.funct3]
+	case opLoadFP:
+		name = map[uint32]string{0x2: "flw", 0x3: "fld"}[i.funct3]
+	case opStoreFP:
+		name = map[uint32]string{0x2: "fsw", 0x3: "fsd"}[i.funct3]
+	case opMAdd:
+		name = "fmadd"
+	case opMSub:
+		name = "fmsub"
+	case opNMSub:
+		name = "fnmsub"
+	case opNMAdd:
+		name = "fnmadd"
+	case opFP:
+		name = "fp"
+	}
+	if name == "" {
+		return "unknown"
+	}
+	return name
+}
+
+// regMnemonic names register-register instructions from their funct7 and funct3 fields.
+func regMnemonic(funct7, funct3 uint32, base, mul [8]string, sub, sra string) string {
+	switch {
+	case funct7 == 0x00:
+		return base[funct3]
+	case funct7 == 0x01:
+		return mul[funct3]
+	case funct7 == 0x20 && funct3 == 0x0:
+		return sub
+	case funct7 == 0x20 && funct3 == 0x5:
+		return sra
+	}
+	return ""
+}
@@ -0,0 +1,564 @@
+// Package riscv provides the implementation of the asmparser interfaces for the RISC-V 64 architecture.
+package riscv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ChainSafe/vm-compat/asmparser"
+	"github.com/ChainSafe/vm-compat/asmparser/elftext"
+)
+
+// Constants defining RISC-V register indexes.
+const (
+	registerZero = 0  // x0, hardwired zero
+	registerRA   = 1  // x1 (Return Address)
+	registerSP   = 2  // x2 (Stack Pointer)
+	registerA7   = 17 // x17, holds the syscall number
+	registerCtxt = 26 // x26, closure context register (REGCTXT) of the Go toolchain
+)
+
+var (
+	// Regular expressions for parsing assembly blocks and instructions.
+	// It's only applicable for a file generated with llvm-objdump
+	blockStartRegex  = regexp.MustCompile(`^([0-9a-fA-F]+)\s+<([^>]+)>:$`)
+	instructionRegex = regexp.MustCompile(`^([0-9a-fA-F]+):\s+((?:[0-9a-fA-F]{2} ){1,3}[0-9a-fA-F]{2})\s+(.*)$`)
+)
+
+// parserImpl implements the asmparser.Parser interface.
+type parserImpl struct {
+	config *asmparser.Config
+}
+
+// NewParser returns a new instance of a RISC-V 64 assembly parser.
+func NewParser(opts ...asmparser.Option) asmparser.Parser {
+	return &parserImpl{config: asmparser.NewConfig(opts...)}
+}
+
+// Parse reads and parses RISC-V assembly into a CallGraph.
+// The input may be llvm-objdump or go tool objdump output, or an ELF binary
+// which is decoded directly. The format is detected from the content.
+// Indirect calls are resolved from the ELF input, or the binary set with asmparser.WithBinary.
+func (p *parserImpl) Parse(r io.Reader) (asmparser.CallGraph, error) {
+	reader := bufio.NewReader(r)
+	binary := p.config.Binary
+	var graph *callGraph
+	var err error
+	switch {
+	case elftext.IsELF(reader):
+		if binary, err = io.ReadAll(reader); err != nil {
+			return nil, fmt.Errorf("error reading elf file: %w", err)
+		}
+		graph, err = parseELF(bytes.NewReader(binary))
+	case isGoObjdump(reader):
+		graph, err = parseGoObjdump(reader)
+	default:
+		graph, err = parseObjdump(reader)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, seg := range graph.segments {
+		seg.buildBlocks()
+	}
+	if err = graph.resolveIndirect(binary); err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
+
+// parseObjdump parses the output of llvm-objdump into a CallGraph.
+// llvm-objdump does not know the compressed extension for Go binaries and prints
+// those instructions as <unknown>, they are decoded from their bytes.
+func parseObjdump(r io.Reader) (*callGraph, error) {
+	builder := newGraphBuilder()
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		lineNum++
+		if matches := blockStartRegex.FindStringSubmatch(line); matches != nil {
+			address, err := strconv.ParseUint(matches[1], 16, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid segment address: %w", err)
+			}
+			builder.startSegment(address, matches[2])
+			continue
+		}
+		matches := instructionRegex.FindStringSubmatch(line)
+		if matches == nil { // Ignore comments and unrecognized lines
+			continue
+		}
+		address, err := strconv.ParseUint(matches[1], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid instruction address: %w", err)
+		}
+		code, err := hex.DecodeString(strings.ReplaceAll(matches[2], " ", ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid RISC-V instruction format: %w", err)
+		}
+		instr, err := decodeBytes(code)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing line %d: %w", lineNum, err)
+		}
+		instr.address = address
+		instr.line = lineNum
+		if err = builder.add(instr); err != nil {
+			return nil, err
+		}
+	}
+	return builder.finish(), nil
+}
+
+// decodeBytes decodes a single instruction stored in little-endian memory order.
+func decodeBytes(code []byte) (*instruction, error) {
+	switch {
+	case len(code) == 2 && isCompressed(binary.LittleEndian.Uint16(code)):
+		return decodeCompressed(binary.LittleEndian.Uint16(code)), nil
+	case len(code) == 4 && !isCompressed(binary.LittleEndian.Uint16(code)):
+		return decodeWord(binary.LittleEndian.Uint32(code)), nil
+	}
+	return nil, fmt.Errorf("invalid instruction encoding: %x", code)
+}
+
+// instruction represents a decoded RISC-V instruction. Compressed instructions are expanded
+// into the fields of their equivalent base instruction.
+type instruction struct {
+	address    uint64
+	raw        uint32 // Raw encoding, 16 bits for compressed instructions.
+	compressed bool
+	op         uint32 // Major opcode of the base instruction.
+	funct3     uint32
+	funct7     uint32
+	rd         uint32
+	rs1        uint32
+	rs2        uint32
+	imm        int64
+	mnemonic   string
+	line       int
+	source     *asmparser.SourcePosition
+	target     uint64 // Destination of direct jumps and calls, valid when hasTarget is set.
+	hasTarget  bool
+}
+
+func (i *instruction) Type() asmparser.InstructionType {
+	switch i.op {
+	case opReg, opReg32, opAMO, opFP, opMAdd, opMSub, opNMSub, opNMAdd:
+		return asmparser.RType
+	case opJAL:
+		return asmparser.JType
+	default:
+		return asmparser.IType
+	}
+}
+
+// OpcodeHex returns the major opcode, or the quadrant (0x0 to 0x2) for compressed instructions.
+func (i *instruction) OpcodeHex() string {
+	if i.compressed {
+		return fmt.Sprintf("0x%x", i.raw&0x3)
+	}
+	return fmt.Sprintf("0x%x", i.op)
+}
+
+// Funct returns the function code distinguishing instructions of the same opcode:
+// funct3 for most formats, funct7<<3|funct3 for register-register instructions,
+// funct5<<3|funct3 for atomics, and nothing for the U and J formats.
+// Compressed instructions use the funct3 of their encoding.
+func (i *instruction) Funct() string {
+	if i.compressed {
+		return fmt.Sprintf("0x%x", (i.raw>>13)&0x7)
+	}
+	switch i.op {
+	case opLUI, opAUIPC, opJAL:
+		return ""
+	case opReg, opReg32, opFP:
+		return fmt.Sprintf("0x%x", i.funct7<<3|i.funct3)
+	case opAMO:
+		return fmt.Sprintf("0x%x", (i.funct7>>2)<<3|i.funct3)
+	default:
+		return fmt.Sprintf("0x%x", i.funct3)
+	}
+}
+
+// Fields returns nil, RISC-V operations are fully selected by opcode and funct.
+func (i *instruction) Fields() map[string]string {
+	return nil
+}
+
+// BaseMnemonic returns the mnemonic without pseudo instruction aliases. Compressed instructions
+// keep their own mnemonic, so they can be allowed separately from their base instruction.
+func (i *instruction) BaseMnemonic() string {
+	if i.compressed {
+		return i.mnemonic
+	}
+	return baseMnemonicOf(i)
+}
+
+func (i *instruction) Mnemonic() string {
+	return i.mnemonic
+}
+
+func (i *instruction) Address() string {
+	return fmt.Sprintf("0x%x", i.address)
+}
+
+func (i *instruction) IsSyscall() bool {
+	return !i.compressed && i.raw == 0x00000073 // ecall
+}
+
+func (i *instruction) Line() int {
+	return i.line
+}
+
+func (i *instruction) Source() *asmparser.SourcePosition {
+	return i.source
+}
+
+// size returns the length of the instruction in bytes.
+func (i *instruction) size() uint64 {
+	if i.compressed {
+		return 2
+	}
+	return 4
+}
+
+// destination returns the integer register written by the instruction.
+func (i *instruction) destination() (uint32, bool) {
+	switch i.op {
+	case opStore, opStoreFP, opBranch, opLoadFP, opMAdd, opMSub, opNMSub, opNMAdd, opMiscMem:
+		return 0, false
+	}
+	return i.rd, i.rd != registerZero
+}
+
+// segment represents a function of the program.
+type segment struct {
+	address      uint64
+	label        string
+	instructions []*instruction
+	blocks       []*block
+	tables       map[int][]uint64 // Targets within the segment of the jumps through a jump table, by index.
+	parents      map[uint64]bool
+}
+
+func newSegment(address uint64, label string) *segment {
+	return &segment{
+		address:      address,
+		label:        strings.TrimSuffix(label, ".abi0"), // match the names of the Go line table
+		instructions: make([]*instruction, 0),
+		tables:       make(map[int][]uint64),
+		parents:      make(map[uint64]bool),
+	}
+}
+
+func (s *segment) Address() string {
+	return fmt.Sprintf("0x%x", s.address)
+}
+
+func (s *segment) Label() string {
+	return s.label
+}
+
+func (s *segment) Instructions() []asmparser.Instruction {
+	instrs := make([]asmparser.Instruction, len(s.instructions))
+	for i, ins := range s.instructions {
+		instrs[i] = ins
+	}
+	return instrs
+}
+
+func (s *segment) Blocks() []asmparser.BasicBlock {
+	return basicBlocks(s.blocks)
+}
+
+// indexOf returns the position of instr in the segment, or -1.
+func (s *segment) indexOf(instr *instruction) int {
+	for i, ins := range s.instructions {
+		if ins == instr {
+			return i
+		}
+	}
+	return -1
+}
+
+// callGraph represents a graph structure implementing asmparser.CallGraph.
+type callGraph struct {
+	segments        map[uint64]*segment
+	indirect        []*asmparser.IndirectCall
+	indirectTargets map[*instruction]map[uint64]bool // Resolved targets of the indirect calls.
+}
+
+func (g *callGraph) Segments() []asmparser.Segment {
+	segments := make([]asmparser.Segment, 0, len(g.segments))
+	for _, addr := range sortedAddresses(g.segments) {
+		segments = append(segments, g.segments[addr])
+	}
+	return segments
+}
+
+func (g *callGraph) ParentsOf(seg asmparser.Segment) []asmparser.Segment {
+	if segObj, ok := seg.(*segment); ok {
+		parents := make([]asmparser.Segment, 0, len(segObj.parents))
+		for _, addr := range sortedAddresses(segObj.parents) {
+			parents = append(parents, g.segments[addr])
+		}
+		return parents
+	}
+	return nil
+}
+
+// sortedAddresses returns the addresses keying m in increasing order, so that the segments
+// are listed in the same order on every run.
+func sortedAddresses[V any](m map[uint64]V) []uint64 {
+	addrs := make([]uint64, 0, len(m))
+	for addr := range m {
+		addrs = append(addrs, addr)
+	}
+	slices.Sort(addrs)
+	return addrs
+}
+
+func (g *callGraph) CallSites(caller, callee asmparser.Segment) []asmparser.Instruction {
+	callerObj, ok := caller.(*segment)
+	if !ok {
+		return nil
+	}
+	calleeObj, ok := callee.(*segment)
+	if !ok {
+		return nil
+	}
+	sites := make([]asmparser.Instruction, 0)
+	for _, instr := range callerObj.instructions {
+		if (instr.hasTarget && instr.target == calleeObj.address) || g.indirectTargets[instr][calleeObj.address] {
+			sites = append(sites, instr)
+		}
+	}
+	return sites
+}
+
+func (g *callGraph) IndirectCalls() []*asmparser.IndirectCall {
+	return g.indirect
+}
+
+// graphBuilder assembles a callGraph from instructions listed in address order.
+type graphBuilder struct {
+	graph   *callGraph
+	current *segment
+}
+
+func newGraphBuilder() *graphBuilder {
+	return &graphBuilder{graph: &callGraph{
+		segments:        make(map[uint64]*segment),
+		indirectTargets: make(map[*instruction]map[uint64]bool),
+	}}
+}
+
+// startSegment starts a new function at address.
+func (b *graphBuilder) startSegment(address uint64, label string) {
+	b.current = newSegment(address, label)
+	b.graph.segments[address] = b.current
+}
+
+// add appends instr to the current function and resolves its jump target. Far calls are
+// emitted as an auipc followed by a jalr on the same register.
+func (b *graphBuilder) add(instr *instruction) error {
+	if b.current == nil {
+		return fmt.Errorf("invalid assembly: instruction encountered before segment definition")
+	}
+	// Functions are padded with zero half-words, the defined illegal instruction
+	if instr.compressed && instr.raw == 0 {
+		return nil
+	}
+	switch instr.op {
+	case opJAL:
+		instr.target = instr.address + uint64(instr.imm) //nolint:gosec
+		instr.hasTarget = true
+	case opJALR:
+		if n := len(b.current.instructions); n > 0 {
+			prev := b.current.instructions[n-1]
+			if prev.op == opAUIPC && prev.rd == instr.rs1 {
+				instr.target = prev.address + uint64(prev.imm+instr.imm) //nolint:gosec
+				instr.hasTarget = true
+			}
+		}
+	}
+	b.current.instructions = append(b.current.instructions, instr)
+	return nil
+}
+
+// finish links every function to the functions it calls or jumps to.
+func (b *graphBuilder) finish() *callGraph {
+	for _, seg := range b.graph.segments {
+		for _, instr := range seg.instructions {
+			if !instr.hasTarget {
+				continue
+			}
+			if callee, ok := b.graph.segments[instr.target]; ok {
+				callee.parents[seg.address] = true
+			}
+		}
+	}
+	return b.graph
+}
+
+// location is where the syscall number is held while walking back the instructions.
+type location struct {
+	register uint32 // Register holding the value, unless stack is set.
+	stack    bool   // The value is spilled to the stack at offset from sp.
+	offset   int64
+	addend   int64 // Constant added to the value of the location.
+}
+
+// origin is where a value resolved by walking back the instructions was set: the constant set
+// by instr, or the reason the value could not be determined there.
+type origin struct {
+	value  int64
+	reason asmparser.UnresolvedReason // Set when the value could not be determined.
+	seg    *segment
+	instr  *instruction
+}
+
+// resolveState identifies a visited step of the value resolution.
+type resolveState struct {
+	seg *segment
+	idx int
+	loc location
+}
+
+// RetrieveSyscallNum extracts the syscall number held in a7 by analyzing the preceding instructions,
+// following register moves, stack spills and calls back into the callers. Every path yields a value,
+// or the reason it could not be determined when the number is computed at run time.
+func (g *callGraph) RetrieveSyscallNum(
+	seg asmparser.Segment,
+	instr asmparser.Instruction,
+) ([]*asmparser.Syscall, []*asmparser.UnresolvedSyscall, error) {
+	ins, ok := instr.(*instruction)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid instruction type: expected RISC-V instruction, got %T", instr)
+	}
+	s, ok := seg.(*segment)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid segment type: expected RISC-V segment, got %T", seg)
+	}
+	idx := s.indexOf(ins)
+	if idx < 0 {
+		return nil, nil, fmt.Errorf("instruction %s not found in segment %s", ins.Address(), s.label)
+	}
+	seen := make(map[resolveState]bool)
+	origins := g.resolveBefore(s, idx, location{register: registerA7}, seen)
+	syscalls := make([]*asmparser.Syscall, 0, len(origins))
+	unresolved := make([]*asmparser.UnresolvedSyscall, 0)
+	for _, origin := range origins {
+		if origin.reason != "" {
+			unresolved = append(unresolved, &asmparser.UnresolvedSyscall{Reason: origin.reason, Segment: origin.seg, Instruction: origin.instr})
+		} else {
+			syscalls = append(syscalls, &asmparser.Syscall{Number: int(origin.value), Segment: origin.seg, Instruction: origin.instr})
+		}
+	}
+	return syscalls, unresolved, nil
+}
+
+// valuesOf returns the distinct values register may hold right before the instruction at idx,
+// along the paths where it could be determined.
+func (g *callGraph) valuesOf(seg *segment, idx int, register uint32) []int64 {
+	values := make([]int64, 0)
+	for _, origin := range g.resolveBefore(seg, idx, location{register: register}, make(map[resolveState]bool)) {
+		if origin.reason == "" && !slices.Contains(values, origin.value) {
+			values = append(values, origin.value)
+		}
+	}
+	return values
+}
+
+// resolveBefore resolves the value of loc from the instructions that may execute right before
+// the instruction at idx: the end of the preceding blocks, and the call sites of the callers
+// when idx may be reached from the entry of the function.
+func (g *callGraph) resolveBefore(seg *segment, idx int, loc location, seen map[resolveState]bool) []origin {
+	state := resolveState{seg: seg, idx: idx, loc: loc}
+	if seen[state] {
+		return nil
+	}
+	seen[state] = true
+
+	preds, entry := seg.predecessors(idx)
+	result := make([]origin, 0)
+	for _, pred := range preds {
+		result = append(result, g.resolve(seg, pred, loc, seen)...)
+	}
+	if !entry {
+		return result
+	}
+	// The value comes from the callers
+	sites := 0
+	for _, parent := range g.ParentsOf(seg) {
+		caller := parent.(*segment) //nolint:forcetypeassert
+		for _, site := range g.CallSites(caller, seg) {
+			sites++
+			result = append(result, g.resolveBefore(caller, caller.indexOf(site.(*instruction)), loc, seen)...) //nolint:forcetypeassert
+		}
+	}
+	if sites == 0 {
+		result = append(result, origin{reason: asmparser.UnresolvedNoCaller, seg: seg, instr: seg.instructions[0]})
+	}
+	return result
+}
+
+// resolve walks back from the instruction at idx to the start of its basic block until the value
+// of loc is known, and continues before the block otherwise.
+//
+//nolint:cyclop
+func (g *callGraph) resolve(seg *segment, idx int, loc location, seen map[resolveState]bool) []origin {
+	start := seg.blockOf(idx).start
+	for i := idx; i >= start; i-- {
+		instr := seg.instructions[i]
+		if loc.stack {
+			switch {
+			case instr.op == opStore && instr.rs1 == registerSP && instr.imm == loc.offset:
+				loc = location{register: instr.rs2, addend: loc.addend}
+			case instr.op == opImm && instr.funct3 == 0x0 && instr.rd == registerSP && instr.rs1 == registerSP:
+				// Stack frame allocation, the slot is relative to the caller's sp
+				loc.offset += instr.imm
+			default:
+				if rd, ok := instr.destination(); ok && rd == registerSP {
+					return []origin{{reason: asmparser.UnresolvedStack, seg: seg, instr: instr}}
+				}
+			}
+			continue
+		}
+
+		rd, ok := instr.destination()
+		if !ok || rd != loc.register {
+			continue
+		}
+		switch {
+		case (instr.op == opImm || instr.op == opImm32) && instr.funct3 == 0x0: // addi, addiw
+			loc.addend += instr.imm
+			if instr.rs1 == registerZero {
+				return []origin{{value: loc.addend, seg: seg, instr: instr}}
+			}
+			loc.register = instr.rs1
+		case instr.op == opLUI:
+			return []origin{{value: instr.imm + loc.addend, seg: seg, instr: instr}}
+		case instr.op == opAUIPC:
+			//nolint:gosec
+			return []origin{{value: int64(instr.address) + instr.imm + loc.addend, seg: seg, instr: instr}}
+		case instr.op == opReg && instr.funct3 == 0x0 && instr.funct7 == 0x0 && instr.rs1 == registerZero: // mv
+			loc.register = instr.rs2
+		case instr.op == opReg && instr.funct3 == 0x0 && instr.funct7 == 0x0 && instr.rs2 == registerZero:
+			loc.register = instr.rs1
+		case instr.op == opLoad && instr.rs1 == registerSP && (instr.funct3 == 0x2 || instr.funct3 == 0x3 || instr.funct3 == 0x6):
+			loc = location{stack: true, offset: instr.imm, addend: loc.addend}
+		default: // computed at runtime
+			return []origin{{reason: asmparser.UnresolvedDynamic, seg: seg, instr: instr}}
+		}
+	}
+	return g.resolveBefore(seg, start, loc, seen)
+}
@@ -0,0 +1,409 @@
+package riscv
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ChainSafe/vm-compat/asmparser"
+	"github.com/ChainSafe/vm-compat/asmparser/elftext/elftexttest"
+	"github.com/ChainSafe/vm-compat/common"
+	"github.com/ChainSafe/vm-compat/disassembler"
+	"github.com/ChainSafe/vm-compat/disassembler/goobjdump"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeCompressed(t *testing.T) {
+	// go tool objdump prints compressed instructions in memory order, e.g. 0x88aa as aa88
+	tests := []struct {
+		half     uint16
+		mnemonic string
+		op       uint32
+		rd       uint32
+		rs1      uint32
+		rs2      uint32
+		imm      int64
+	}{
+		{half: 0x88aa, mnemonic: "c.mv", op: opReg, rd: 17, rs2: 10},                            // ADD X10, X0, X17
+		{half: 0xe006, mnemonic: "c.sdsp", op: opStore, rs1: registerSP, rs2: registerRA},       // MOV X1, (X2)
+		{half: 0x106c, mnemonic: "c.addi4spn", op: opImm, rd: 11, rs1: registerSP, imm: 44},     // ADDI $44, X2, X11
+		{half: 0x557d, mnemonic: "c.li", op: opImm, rd: 10, imm: -1},                            // ADDI $-1, X0, X10
+		{half: 0x72fd, mnemonic: "c.lui", op: opLUI, rd: 5, imm: -4096},                         // LUI $-1, X5
+		{half: 0x1161, mnemonic: "c.addi", op: opImm, rd: registerSP, rs1: registerSP, imm: -8}, // ADDI $-8, X2, X2
+		{half: 0x713d, mnemonic: "c.addi16sp", op: opImm, rd: registerSP, rs1: registerSP, imm: -32},
+		{half: 0x60a2, mnemonic: "c.ldsp", op: opLoad, rd: registerRA, rs1: registerSP, imm: 8}, // MOV 8(X2), X1
+		{half: 0x8082, mnemonic: "c.jr", op: opJALR, rs1: registerRA},                           // RET
+	}
+	for _, tt := range tests {
+		instr := decodeCompressed(tt.half)
+		assert.Equal(t, tt.mnemonic, instr.Mnemonic(), "0x%04x", tt.half)
+		assert.Equal(t, tt.op, instr.op, "0x%04x", tt.half)
+		assert.Equal(t, tt.rd, instr.rd, "0x%04x", tt.half)
+		assert.Equal(t, tt.rs1, instr.rs1, "0x%04x", tt.half)
+		assert.Equal(t, tt.rs2, instr.rs2, "0x%04x", tt.half)
+		assert.Equal(t, tt.imm, instr.imm, "0x%04x", tt.half)
+		assert.Equal(t, "0x"+string("012"[tt.half&0x3]), instr.OpcodeHex())
+	}
+}
+
+func TestDecodeWord(t *testing.T) {
+	tests := []struct {
+		word     uint32
+		mnemonic string
+		opcode   string
+		funct    string
+	}{
+		{word: 0x010db303, mnemonic: "ld", opcode: "0x3", funct: "0x3"},
+		{word: 0x00236663, mnemonic: "bltu", opcode: "0x63", funct: "0x6"},
+		{word: 0x05e00893, mnemonic: "li", opcode: "0x13", funct: "0x0"},
+		{word: 0x00000073, mnemonic: "ecall", opcode: "0x73", funct: "0x0"},
+		{word: 0x40a00633, mnemonic: "sub", opcode: "0x33", funct: "0x100"},
+		{word: 0x02b50533, mnemonic: "mul", opcode: "0x33", funct: "0x8"},
+		{word: 0x0c05302f, mnemonic: "amoswap.d", opcode: "0x2f", funct: "0xb"},
+		{word: 0x0000b497, mnemonic: "auipc", opcode: "0x17", funct: ""},
+	}
+	for _, tt := range tests {
+		instr := decodeWord(tt.word)
+		assert.Equal(t, tt.mnemonic, instr.Mnemonic(), "0x%08x", tt.word)
+		assert.Equal(t, tt.opcode, instr.OpcodeHex(), "0x%08x", tt.word)
+		assert.Equal(t, tt.funct, instr.Funct(), "0x%08x", tt.word)
+	}
+	assert.Equal(t, "addi", decodeWord(0x05e00893).BaseMnemonic()) // li
+	assert.Equal(t, "jalr", decodeWord(0x00008067).BaseMnemonic()) // ret
+}
+
+func TestParse(t *testing.T) {
+	goObjdump := `TEXT main.main(SB) /app/main.go
+  main.go:5		0x11000			03f00513		ADDI $63, X0, X10
+  main.go:5		0x11004			008000ef		JAL X1, internal/runtime/syscall/linux.Syscall6(SB)
+  main.go:6		0x11008			00008067		RET
+TEXT internal/runtime/syscall/linux.Syscall6(SB) /usr/local/go/src/internal/runtime/syscall/linux/asm_linux_riscv64.s
+  asm_linux_riscv64.s:25	0x1100c			aa88			ADD X10, X0, X17
+  asm_linux_riscv64.s:32	0x1100e			00000073		ECALL
+  asm_linux_riscv64.s:38	0x11012			00008067		RET
+TEXT runtime.exit.abi0(SB) /usr/local/go/src/runtime/sys_linux_riscv64.s
+  sys_linux_riscv64.s:55	0x11016			05e00893		ADDI $94, X0, X17
+  sys_linux_riscv64.s:56	0x1101a			00000073		ECALL
+`
+	llvmObjdump := `/app/sample:	file format elf64-littleriscv
+
+Disassembly of section .text:
+
+0000000000011000 <main.main>:
+   11000: 13 05 f0 03  	li	a0, 63
+   11004: ef 00 80 00  	jal	0x1100c <internal/runtime/syscall/linux.Syscall6.abi0>
+   11008: 67 80 00 00  	ret
+
+000000000001100c <internal/runtime/syscall/linux.Syscall6.abi0>:
+   1100c: aa 88        	<unknown>
+   1100e: 73 00 00 00  	ecall
+   11012: 67 80 00 00  	ret
+   11016: 00 00        	<unknown>
+
+0000000000011018 <runtime.exit.abi0>:
+   11018: 93 08 e0 05  	li	a7, 94
+   1101c: 73 00 00 00  	ecall
+`
+	for name, content := range map[string]string{"goobjdump": goObjdump, "llvm": llvmObjdump} {
+		t.Run(name, func(t *testing.T) {
+			graph, err := NewParser().Parse(strings.NewReader(content))
+			require.NoError(t, err)
+
+			segments := make(map[string]asmparser.Segment)
+			numbers := make([]int, 0)
+			for _, seg := range graph.Segments() {
+				segments[seg.Label()] = seg
+				for _, instr := range seg.Instructions() {
+					if !instr.IsSyscall() {
+						continue
+					}
+					syscalls, _, err := graph.RetrieveSyscallNum(seg, instr)
+					require.NoError(t, err)
+					for _, syscall := range syscalls {
+						numbers = append(numbers, syscall.Number)
+					}
+				}
+			}
+			assert.ElementsMatch(t, []int{63, 94}, numbers)
+
+			// The .abi0 suffix of assembly functions is dropped
+			syscall6 := segments["internal/runtime/syscall/linux.Syscall6"]
+			require.NotNil(t, syscall6)
+			assert.Len(t, syscall6.Instructions(), 3)
+			parents := graph.ParentsOf(syscall6)
+			require.Len(t, parents, 1)
+			assert.Equal(t, "main.main", parents[0].Label())
+			sites := graph.CallSites(parents[0], syscall6)
+			require.Len(t, sites, 1)
+			assert.Equal(t, "0x11004", sites[0].Address())
+		})
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	content := `/app/sample:	file format elf64-littleriscv
+
+Disassembly of section .text:
+
+0000000000011000 <main.main>:
+   11000: 93 08 00 04  	li	a7, 64
+   11004: 6f 00 c0 00  	j	0x11010 <main.main+0x10>
+   11008: 93 08 d0 05  	li	a7, 93
+   1100c: 67 80 00 00  	ret
+   11010: 73 00 00 00  	ecall
+   11014: e3 1e 05 fe  	bnez	a0, 0x11010 <main.main+0x10>
+   11018: 67 80 00 00  	ret
+`
+	graph, err := NewParser().Parse(strings.NewReader(content))
+	require.NoError(t, err)
+	require.Len(t, graph.Segments(), 1)
+	seg := graph.Segments()[0]
+
+	blocks := seg.Blocks()
+	addresses := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		addresses = append(addresses, b.Address())
+	}
+	require.Equal(t, []string{"0x11000", "0x11008", "0x11010", "0x11018"}, addresses)
+
+	successors := func(b asmparser.BasicBlock) []string {
+		result := make([]string, 0)
+		for _, succ := range b.Successors() {
+			result = append(result, succ.Address())
+		}
+		return result
+	}
+	assert.Equal(t, []string{"0x11010"}, successors(blocks[0]))
+	assert.Empty(t, successors(blocks[1]))
+	// The loop branches back to its own block
+	assert.Equal(t, []string{"0x11010", "0x11018"}, successors(blocks[2]))
+	assert.Len(t, blocks[2].Predecessors(), 2)
+	assert.Empty(t, successors(blocks[3]))
+
+	// The syscall number only comes from the paths that reach the ecall
+	syscalls, _, err := graph.RetrieveSyscallNum(seg, seg.Instructions()[4])
+	require.NoError(t, err)
+	require.Len(t, syscalls, 1)
+	assert.Equal(t, 64, syscalls[0].Number)
+}
+
+func TestUnresolvedSyscallNum(t *testing.T) {
+	content := `/app/sample:	file format elf64-littleriscv
+
+Disassembly of section .text:
+
+0000000000011000 <main.main>:
+   11000: 83 38 85 00  	ld	a7, 8(a0)
+   11004: 73 00 00 00  	ecall
+   11008: 67 80 00 00  	ret
+`
+	graph, err := NewParser().Parse(strings.NewReader(content))
+	require.NoError(t, err)
+	require.Len(t, graph.Segments(), 1)
+	seg := graph.Segments()[0]
+
+	syscalls, unresolved, err := graph.RetrieveSyscallNum(seg, seg.Instructions()[1])
+	require.NoError(t, err)
+	assert.Empty(t, syscalls)
+	require.Len(t, unresolved, 1)
+	assert.Equal(t, asmparser.UnresolvedDynamic, unresolved[0].Reason)
+	assert.Equal(t, "main.main", unresolved[0].Segment.Label())
+	assert.Equal(t, "0x11000", unresolved[0].Instruction.Address())
+}
+
+func TestJumpTable(t *testing.T) {
+	content := `/app/sample:	file format elf64-littleriscv
+
+Disassembly of section .text:
+
+0000000000011000 <main.dispatch>:
+   11000: 93 08 d0 05  	li	a7, 93
+   11004: 93 07 30 00  	li	a5, 3
+   11008: 63 e4 a7 02  	bltu	a5, a0, 0x11030 <main.dispatch+0x30>
+   1100c: 97 f7 00 00  	auipc	a5, 15
+   11010: 93 87 47 00  	addi	a5, a5, 4
+   11014: 13 15 25 00  	slli	a0, a0, 2
+   11018: 33 05 f5 00  	add	a0, a0, a5
+   1101c: 03 25 05 00  	lw	a0, 0(a0)
+   11020: 33 05 f5 00  	add	a0, a0, a5
+   11024: 67 00 05 00  	jr	a0
+   11028: 73 00 00 00  	ecall
+   1102c: 67 80 00 00  	ret
+   11030: 67 80 00 00  	ret
+`
+	// Position independent code stores the offsets of the targets from the table at 0x20010
+	table := make([]byte, 16)
+	for _, target := range []int64{0x11028, 0x11030} {
+		table = binary.LittleEndian.AppendUint32(table, uint32(target-0x20010)) //nolint:gosec
+	}
+	table = binary.LittleEndian.AppendUint32(table, 0)
+	data := elftexttest.DataELF(t, elf.EM_RISCV, binary.LittleEndian, 0x20000, table)
+	graph, err := NewParser(asmparser.WithBinary(data)).Parse(strings.NewReader(content))
+	require.NoError(t, err)
+	require.Len(t, graph.Segments(), 1)
+	seg := graph.Segments()[0]
+
+	calls := graph.IndirectCalls()
+	require.Len(t, calls, 1)
+	call := calls[0]
+	assert.Equal(t, "0x11024", call.Instruction.Address())
+	assert.Equal(t, asmparser.IndirectJumpTable, call.Kind)
+	assert.Empty(t, call.Targets)
+	require.Len(t, call.Blocks, 2)
+	assert.Equal(t, "0x11028", call.Blocks[0].Address())
+	assert.Equal(t, "0x11030", call.Blocks[1].Address())
+
+	// The ecall is only reached through the table
+	blocks := seg.Blocks()
+	require.Len(t, blocks, 4)
+	assert.ElementsMatch(t, call.Blocks, blocks[1].Successors())
+	syscalls, _, err := graph.RetrieveSyscallNum(seg, seg.Instructions()[10])
+	require.NoError(t, err)
+	require.Len(t, syscalls, 1)
+	assert.Equal(t, 93, syscalls[0].Number)
+}
+
+func TestParseELF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sample")
+	require.NoError(t, common.BuildBinary("../../examples/sample.go", "linux", "riscv64", path, nil))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	graph, err := NewParser().Parse(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	var mainSegment asmparser.Segment
+	for _, seg := range graph.Segments() {
+		if seg.Label() == "main.main" {
+			mainSegment = seg
+		}
+	}
+	require.NotNil(t, mainSegment)
+	// Go functions start by loading the stack guard from g
+	assert.Equal(t, "ld", mainSegment.Instructions()[0].Mnemonic())
+	covered := 0
+	for _, b := range mainSegment.Blocks() {
+		covered += len(b.Instructions())
+	}
+	assert.Greater(t, len(mainSegment.Blocks()), 1)
+	assert.Equal(t, len(mainSegment.Instructions()), covered)
+	// write and exit_group
+	numbers := syscallNumbers(t, graph)
+	assert.True(t, numbers[64])
+	assert.True(t, numbers[94])
+
+	resolved := make(map[asmparser.IndirectKind]bool)
+	for _, call := range graph.IndirectCalls() {
+		if len(call.Targets) > 0 {
+			resolved[call.Kind] = true
+			assert.Contains(t, graph.ParentsOf(call.Targets[0]), call.Segment)
+		}
+	}
+	assert.True(t, resolved[asmparser.IndirectItab])
+	assert.True(t, resolved[asmparser.IndirectClosure])
+}
+
+// syscallNumbers returns the numbers of the syscalls of graph.
+func syscallNumbers(t *testing.T, graph asmparser.CallGraph) map[int]bool {
+	t.Helper()
+	numbers := make(map[int]bool)
+	for _, seg := range graph.Segments() {
+		for _, instr := range seg.Instructions() {
+			if !instr.IsSyscall() {
+				continue
+			}
+			syscalls, _, err := graph.RetrieveSyscallNum(seg, instr)
+			require.NoError(t, err)
+			for _, syscall := range syscalls {
+				numbers[syscall.Number] = true
+			}
+		}
+	}
+	return numbers
+}
+
+func TestGoObjdumpSourceFiles(t *testing.T) {
+	content := `TEXT main.main(SB) /app/main.go
+  main.go:5		0x11000			03f00513		ADDI $63, X0, X10
+  util.go:9		0x11004			008000ef		JAL X1, tool/main.main(SB)
+  doc.go:3		0x11008			00008067		RET
+TEXT tool/main.main(SB) /app/tool/main.go
+  main.go:7		0x1100c			03f00513		ADDI $63, X0, X10
+  main.go:8		0x11010			00008067		RET
+TEXT main.helper(SB) /app/util.go
+  main.go:12		0x11014			0001			NOP
+  util.go:9		0x11016			00008067		RET
+TEXT tool/main.helper(SB) /app/tool/doc.go
+  doc.go:3		0x1101a			00008067		RET
+TEXT tool/main.other(SB) /app/other/doc.go
+  doc.go:4		0x1101e			00008067		RET
+`
+	graph, err := NewParser().Parse(strings.NewReader(content))
+	require.NoError(t, err)
+	files := make(map[string]string)
+	for _, seg := range graph.Segments() {
+		for _, instr := range seg.Instructions() {
+			files[instr.Address()] = instr.Source().File
+		}
+	}
+	// Files sharing a base name resolve to the file of their function, inlined files to the
+	// only function file with that name, and are left as is when several files have it
+	assert.Equal(t, map[string]string{
+		"0x11000": "/app/main.go",
+		"0x11004": "/app/util.go",
+		"0x11008": "doc.go",
+		"0x1100c": "/app/tool/main.go",
+		"0x11010": "/app/tool/main.go",
+		"0x11014": "main.go",
+		"0x11016": "/app/util.go",
+		"0x1101a": "/app/tool/doc.go",
+		"0x1101e": "/app/other/doc.go",
+	}, files)
+}
+
+func TestParseGoObjdump(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sample")
+	require.NoError(t, common.BuildBinary("../../examples/sample.go", "linux", "riscv64", path, nil))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	disassembly, err := goobjdump.New("linux", "riscv64", nil).Disassemble(disassembler.SourceBinary, path)
+	require.NoError(t, err)
+
+	graph, err := NewParser(asmparser.WithBinary(data)).Parse(disassembly)
+	require.NoError(t, err)
+	elfGraph, err := NewParser().Parse(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	// go tool objdump and the ELF decoder yield the same functions
+	elfSegments := make(map[string]asmparser.Segment)
+	for _, seg := range elfGraph.Segments() {
+		elfSegments[seg.Address()] = seg
+	}
+	var mainSegment asmparser.Segment
+	for _, seg := range graph.Segments() {
+		if seg.Label() == "main.main" {
+			mainSegment = seg
+		}
+		elfSegment := elfSegments[seg.Address()]
+		require.NotNil(t, elfSegment, seg.Label())
+		assert.Equal(t, elfSegment.Label(), seg.Label())
+		assert.Len(t, seg.Instructions(), len(elfSegment.Instructions()), seg.Label())
+		assert.Len(t, graph.ParentsOf(seg), len(elfGraph.ParentsOf(elfSegment)), seg.Label())
+	}
+	require.NotNil(t, mainSegment)
+	assert.Equal(t, syscallNumbers(t, elfGraph), syscallNumbers(t, graph))
+	assert.Len(t, graph.IndirectCalls(), len(elfGraph.IndirectCalls()))
+
+	// Every instruction of main.main carries its position in the source file
+	source, err := filepath.Abs("../../examples/sample.go")
+	require.NoError(t, err)
+	for _, instr := range mainSegment.Instructions() {
+		require.NotNil(t, instr.Source(), instr.Address())
+		assert.Equal(t, source, instr.Source().File)
+	}
+}
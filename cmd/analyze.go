@@ -1,13 +1,19 @@
 package cmd
 
 import (
+	"debug/elf"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/ChainSafe/vm-compat/analyzer"
 	"github.com/ChainSafe/vm-compat/analyzer/opcode"
 	"github.com/ChainSafe/vm-compat/analyzer/syscall"
+	"github.com/ChainSafe/vm-compat/common"
 	"github.com/ChainSafe/vm-compat/disassembler"
 	"github.com/ChainSafe/vm-compat/disassembler/manager"
 	"github.com/ChainSafe/vm-compat/profile"
@@ -28,9 +34,15 @@ var (
 		Usage:    "Type of analysis to perform. Options: opcode, syscall",
 		Required: false,
 	}
+	DisassemblerFlag = &cli.StringFlag{
+		Name:     "disassembler",
+		Usage:    "Disassembler to use. Options: native, objdump (requires llvm-objdump), goobjdump (go tool objdump, riscv64 only)",
+		Required: false,
+		Value:    "native",
+	}
 	DisassemblyOutputFlag = &cli.PathFlag{
 		Name:     "disassembly-output-path",
-		Usage:    "File path to store the disassembled assembly code",
+		Usage:    "File path to store the disassembled assembly code. Requires the objdump or goobjdump disassembler",
 		Required: false,
 	}
 	FormatFlag = &cli.StringFlag{
@@ -44,12 +56,35 @@ var (
 		Usage:    "output file path for report. Default: stdout",
 		Required: false,
 	}
+	BinaryFlag = &cli.BoolFlag{
+		Name:     "binary",
+		Usage:    "treat the input as a compiled ELF binary. Auto-detected from the ELF magic when not set",
+		Required: false,
+		Value:    false,
+	}
 	TraceFlag = &cli.BoolFlag{
 		Name:     "with-trace",
 		Usage:    "enable full stack trace output",
 		Required: false,
 		Value:    false,
 	}
+	SyscallAnalyzerFlag = &cli.StringFlag{
+		Name:     "syscall-analyzer",
+		Usage:    "Syscall analyzer to use. Options: assembly (disassembled binary), go (SSA of the Go source), compare (both)",
+		Required: false,
+		Value:    "assembly",
+	}
+	CallGraphFlag = &cli.StringFlag{
+		Name:     "call-graph",
+		Usage:    "Call graph algorithm of the SSA analysis, overriding the profile. Options: cha, rta, vta. Default: rta",
+		Required: false,
+		Action: func(_ *cli.Context, algorithm string) error {
+			if err := profile.ValidateCallGraph(algorithm); err != nil {
+				return fmt.Errorf("--call-graph: %w", err)
+			}
+			return nil
+		},
+	}
 )
 
 func CreateAnalyzeCommand(action cli.ActionFunc) *cli.Command {
@@ -58,14 +93,18 @@ func CreateAnalyzeCommand(action cli.ActionFunc) *cli.Command {
 		Usage:       "Checks the program compatibility against the VM profile",
 		Description: "Checks the program compatibility against the VM profile",
 		Action:      action,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			VMProfileFlag,
 			AnalysisTypeFlag,
+			DisassemblerFlag,
 			DisassemblyOutputFlag,
 			FormatFlag,
 			ReportOutputPathFlag,
+			BinaryFlag,
 			TraceFlag,
-		},
+			SyscallAnalyzerFlag,
+			CallGraphFlag,
+		}, buildFlags...),
 	}
 }
 
@@ -77,22 +116,67 @@ func AnalyzeCompatibility(ctx *cli.Context) error {
 	if err != nil {
 		return fmt.Errorf("error loading profile: %w", err)
 	}
+	if callGraph := ctx.String(CallGraphFlag.Name); callGraph != "" {
+		prof.CallGraph = callGraph
+	}
 
 	source := ctx.Args().First()
+	disassemblerType := ctx.String(DisassemblerFlag.Name)
 	disassemblyPath := ctx.Path(DisassemblyOutputFlag.Name)
 	format := ctx.String(FormatFlag.Name)
 	reportOutputPath := ctx.Path(ReportOutputPathFlag.Name)
 	analysisType := ctx.String(AnalysisTypeFlag.Name)
 	withTrace := ctx.Bool(TraceFlag.Name)
-
-	disassemblyPath, err = disassemble(prof, source, disassemblyPath)
+	syscallAnalyzer := ctx.String(SyscallAnalyzerFlag.Name)
+	if !slices.Contains([]string{"assembly", "go", "compare"}, syscallAnalyzer) {
+		return fmt.Errorf("invalid syscall analyzer: %s", syscallAnalyzer)
+	}
+	build, err := buildConfig(ctx)
+	if err != nil {
+		return err
+	}
+	dis, err := newDisassembler(prof, build, disassemblerType)
 	if err != nil {
-		return fmt.Errorf("error disassembling the file: %w", err)
+		return err
+	}
+	// The native disassembler decodes the binary itself and has no text output to store
+	if disassemblyPath != "" && disassemblerType == "native" {
+		return fmt.Errorf("--disassembly-output-path needs a text disassembly, use the objdump or goobjdump disassembler")
 	}
 
-	issues, err := analyze(prof, disassemblyPath, analysisType, withTrace)
+	targets, err := resolveTargets(prof, build, source, ctx.Bool(BinaryFlag.Name))
 	if err != nil {
-		return fmt.Errorf("analysis failed: %w", err)
+		return fmt.Errorf("unable to resolve source: %w", err)
+	}
+
+	issues := make([]*analyzer.Issue, 0)
+	for _, target := range targets {
+		if syscallAnalyzer != "assembly" && target.mode == disassembler.SourceBinary {
+			return fmt.Errorf("the %s syscall analyzer needs the Go source of %s, not a binary", syscallAnalyzer, target.name)
+		}
+		// The SSA analysis loads the Go packages, the disassembly is only needed by the other analyzers
+		source := &analyzer.Program{Path: target.path}
+		program := source
+		if analysisType != "syscall" || syscallAnalyzer != "go" {
+			outputPath := disassemblyPath
+			if outputPath != "" && len(targets) > 1 {
+				// Main packages of different directories may share a base name
+				outputPath = fmt.Sprintf("%s.%s", disassemblyPath, strings.ReplaceAll(target.name, "/", "_"))
+			}
+			program, err = disassemble(prof, build, dis, target.mode, target.path, outputPath)
+			if err != nil {
+				return fmt.Errorf("error disassembling %s: %w", target.name, err)
+			}
+		}
+
+		binaryIssues, err := analyze(prof, program, analysisType, syscallAnalyser(prof, build, syscallAnalyzer, program, source), withTrace)
+		if err != nil {
+			return fmt.Errorf("analysis of %s failed: %w", target.name, err)
+		}
+		for _, issue := range binaryIssues {
+			issue.Binary = target.name
+		}
+		issues = append(issues, binaryIssues...)
 	}
 
 	if err := writeReport(issues, format, reportOutputPath, prof); err != nil {
@@ -101,35 +185,172 @@ func AnalyzeCompatibility(ctx *cli.Context) error {
 	return nil
 }
 
+// target is a single program to build and analyze.
+type target struct {
+	name string // Name of the binary, used to key the issues in the report.
+	path string
+	mode disassembler.Source
+}
+
+// resolveTargets expands the source argument into the programs to analyze.
+// A compiled binary or a Go source file is a single program, while a directory, module
+// or package pattern yields one program per main package.
+func resolveTargets(prof *profile.VMProfile, build *common.BuildConfig, source string, binary bool) ([]*target, error) {
+	if binary {
+		return []*target{{name: filepath.Base(source), path: source, mode: disassembler.SourceBinary}}, nil
+	}
+	if strings.HasSuffix(source, ".go") {
+		return []*target{{name: filepath.Base(source), path: source, mode: disassembler.SourceFile}}, nil
+	}
+	if isBinary, err := isELFBinary(source); err == nil && isBinary {
+		return []*target{{name: filepath.Base(source), path: source, mode: disassembler.SourceBinary}}, nil
+	}
+
+	mains, err := common.FindMainPackages(source, prof.GOOS, prof.GOARCH, build)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]*target, 0, len(mains))
+	for _, pkg := range mains {
+		targets = append(targets, &target{name: pkg.ImportPath, path: pkg.Dir, mode: disassembler.SourceFile})
+	}
+	return targets, nil
+}
+
+// newDisassembler returns the disassembler of the given type for the platform of the profile.
+func newDisassembler(prof *profile.VMProfile, build *common.BuildConfig, disassemblerType string) (disassembler.Disassembler, error) {
+	var typ disassembler.Type
+	switch disassemblerType {
+	case "native":
+		typ = disassembler.TypeNative
+	case "objdump":
+		typ = disassembler.TypeObjdump
+	case "goobjdump":
+		typ = disassembler.TypeGoObjdump
+	default:
+		return nil, fmt.Errorf("invalid disassembler: %s", disassemblerType)
+	}
+	return manager.NewDisassembler(typ, prof.GOOS, prof.GOARCH, build)
+}
+
 // disassemble extracts assembly output for analysis.
-func disassemble(prof *profile.VMProfile, path, outputPath string) (string, error) {
-	dis, err := manager.NewDisassembler(disassembler.TypeObjdump, prof.GOOS, prof.GOARCH)
+// The output is only written to a file when outputPath is set.
+func disassemble(
+	prof *profile.VMProfile,
+	build *common.BuildConfig,
+	dis disassembler.Disassembler,
+	mode disassembler.Source,
+	path, outputPath string,
+) (*analyzer.Program, error) {
+	// Build the program once, so the binary stays available to map instructions to Go source lines
+	binaryPath := path
+	if mode == disassembler.SourceFile {
+		built, cleanup, err := common.BuildTempBinary(path, prof.GOOS, prof.GOARCH, build)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		binaryPath = built
+	}
+	binary, err := os.ReadFile(binaryPath)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("unable to read binary: %w", err)
 	}
 
-	if outputPath == "" {
-		outputPath = filepath.Join(os.TempDir(), "temp_assembly_output")
+	reader, err := dis.Disassemble(disassembler.SourceBinary, binaryPath)
+	if err != nil {
+		return nil, err
+	}
+	disassembly, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read disassembly: %w", err)
+	}
+
+	program := &analyzer.Program{Path: path, Disassembly: disassembly, Binary: binary}
+	if outputPath != "" {
+		absOutputPath, err := filepath.Abs(outputPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path of output file: %w", err)
+		}
+		if err = os.WriteFile(absOutputPath, disassembly, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write to output file: %w", err)
+		}
+		// Call stacks refer to lines of the stored disassembly
+		program.DisassemblyPath = absOutputPath
 	}
+	return program, nil
+}
 
-	_, err = dis.Disassemble(disassembler.SourceFile, path, outputPath)
-	return outputPath, err
+// isELFBinary reports whether the file at path starts with the ELF magic number.
+func isELFBinary(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if !info.Mode().IsRegular() {
+		return false, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	magic := make([]byte, len(elf.ELFMAG))
+	if _, err = io.ReadFull(file, magic); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	return string(magic) == elf.ELFMAG, nil
+}
+
+// syscallAnalysis is a syscall analyzer with the program it analyzes.
+type syscallAnalysis struct {
+	analyzer analyzer.Analyzer
+	program  *analyzer.Program
+}
+
+// syscallAnalyser returns the syscall analyzer of the given kind. The assembly and compare analyzers
+// read the disassembled program, while the go analyzer loads the packages of the source.
+func syscallAnalyser(
+	prof *profile.VMProfile,
+	build *common.BuildConfig,
+	kind string,
+	program, source *analyzer.Program,
+) *syscallAnalysis {
+	switch kind {
+	case "go":
+		return &syscallAnalysis{analyzer: syscall.NewGOSyscallAnalyser(prof, build), program: source}
+	case "compare":
+		return &syscallAnalysis{analyzer: syscall.NewCrossSyscallAnalyser(prof, build), program: program}
+	}
+	return &syscallAnalysis{analyzer: syscall.NewAssemblySyscallAnalyser(prof), program: program}
 }
 
 // analyze runs the selected analyzer(s).
-func analyze(prof *profile.VMProfile, disassemblyPath, mode string, withTrace bool) ([]*analyzer.Issue, error) {
+func analyze(
+	prof *profile.VMProfile,
+	program *analyzer.Program,
+	mode string,
+	sys *syscallAnalysis,
+	withTrace bool,
+) ([]*analyzer.Issue, error) {
 	if mode == "opcode" {
-		return opcode.NewAnalyser(prof).Analyze(disassemblyPath, withTrace)
+		return opcode.NewAnalyser(prof).Analyze(program, withTrace)
 	}
 	if mode == "syscall" {
-		return syscall.NewAssemblySyscallAnalyser(prof).Analyze(disassemblyPath, withTrace)
+		return sys.analyzer.Analyze(sys.program, withTrace)
 	}
 	// by default analyze both
-	opIssues, err := opcode.NewAnalyser(prof).Analyze(disassemblyPath, withTrace)
+	opIssues, err := opcode.NewAnalyser(prof).Analyze(program, withTrace)
 	if err != nil {
 		return nil, err
 	}
-	sysIssues, err := syscall.NewAssemblySyscallAnalyser(prof).Analyze(disassemblyPath, withTrace)
+	sysIssues, err := sys.analyzer.Analyze(sys.program, withTrace)
 	if err != nil {
 		return nil, err
 	}
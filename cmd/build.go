@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ChainSafe/vm-compat/common"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	BuildTagsFlag = &cli.StringSliceFlag{
+		Name:     "tags",
+		Usage:    "Build tags applied when compiling and loading the program",
+		Required: false,
+	}
+	LDFlagsFlag = &cli.StringFlag{
+		Name:     "ldflags",
+		Usage:    "Linker flags applied when compiling and loading the program",
+		Required: false,
+	}
+	TrimPathFlag = &cli.BoolFlag{
+		Name:     "trimpath",
+		Usage:    "Remove file system paths from the compiled program",
+		Required: false,
+		Value:    false,
+	}
+	ModFlag = &cli.StringFlag{
+		Name:     "mod",
+		Usage:    "Module download mode passed to the go command. Options: readonly, vendor, mod",
+		Required: false,
+	}
+	CGOEnabledFlag = &cli.BoolFlag{
+		Name:     "cgo-enabled",
+		Usage:    "Value of CGO_ENABLED when compiling and loading the program",
+		Required: false,
+		Value:    false,
+	}
+	BuildEnvFlag = &cli.StringSliceFlag{
+		Name:     "env",
+		Usage:    "Extra environment variables for the go command in KEY=VALUE form, can be repeated",
+		Required: false,
+	}
+)
+
+// buildFlags are shared by the commands compiling or loading Go programs.
+var buildFlags = []cli.Flag{
+	BuildTagsFlag,
+	LDFlagsFlag,
+	TrimPathFlag,
+	ModFlag,
+	CGOEnabledFlag,
+	BuildEnvFlag,
+}
+
+// buildConfig creates the build configuration from the command flags.
+func buildConfig(ctx *cli.Context) (*common.BuildConfig, error) {
+	env := ctx.StringSlice(BuildEnvFlag.Name)
+	for _, kv := range env {
+		if !strings.Contains(kv, "=") {
+			return nil, fmt.Errorf("invalid environment variable %q, expected KEY=VALUE", kv)
+		}
+	}
+	return &common.BuildConfig{
+		Tags:       ctx.StringSlice(BuildTagsFlag.Name),
+		LDFlags:    ctx.String(LDFlagsFlag.Name),
+		TrimPath:   ctx.Bool(TrimPathFlag.Name),
+		Mod:        ctx.String(ModFlag.Name),
+		CGOEnabled: ctx.Bool(CGOEnabledFlag.Name),
+		Env:        env,
+	}, nil
+}
@@ -2,6 +2,8 @@
 package cmd
 
 import (
+	"bytes"
+	"debug/elf"
 	"fmt"
 	"os"
 	"strings"
@@ -33,11 +35,12 @@ func CreateTraceCommand(action cli.ActionFunc) *cli.Command {
 		Usage:       "Generates stack trace for a given function",
 		Description: "Generates stack trace for a given function",
 		Action:      action,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			VMProfileFlag,
 			FunctionNameFlag,
 			SourceTypeFlag,
-		},
+			CallGraphFlag,
+		}, buildFlags...),
 	}
 }
 
@@ -49,19 +52,37 @@ func TraceCaller(ctx *cli.Context) error {
 	if err != nil {
 		return fmt.Errorf("error loading profile: %w", err)
 	}
+	if callGraph := ctx.String(CallGraphFlag.Name); callGraph != "" {
+		prof.CallGraph = callGraph
+	}
 
 	function := ctx.String(FunctionNameFlag.Name)
 	sourceType := ctx.String(SourceTypeFlag.Name)
 	path := ctx.Args().First()
 
-	var analyzer analyzer.Analyzer
+	program := &analyzer.Program{Path: path}
+	var tracer analyzer.Analyzer
 	if sourceType == "go" {
-		analyzer = syscall.NewGOSyscallAnalyser(prof)
+		build, err := buildConfig(ctx)
+		if err != nil {
+			return err
+		}
+		tracer = syscall.NewGOSyscallAnalyser(prof, build)
 	} else {
-		analyzer = syscall.NewAssemblySyscallAnalyser(prof)
+		tracer = syscall.NewAssemblySyscallAnalyser(prof)
+		program.Disassembly, err = os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("unable to read disassembly: %w", err)
+		}
+		// Frames point at the lines of a text disassembly, or at Go source lines of a binary
+		if bytes.HasPrefix(program.Disassembly, []byte(elf.ELFMAG)) {
+			program.Binary = program.Disassembly
+		} else {
+			program.DisassemblyPath = path
+		}
 	}
 
-	callStack, err := analyzer.TraceStack(path, function)
+	callStack, err := tracer.TraceStack(program, function)
 	if err != nil {
 		return err
 	}
@@ -74,12 +95,21 @@ func TraceCaller(ctx *cli.Context) error {
 }
 
 func printCallStack(source *analyzer.CallStack, str string) string {
-	fileInfo := fmt.Sprintf(
-		" \033[94m\033]8;;file://%s:%d\033\\%s:%d\033]8;;\033\\\033[0m",
-		source.AbsPath, source.Line, source.File, source.Line,
-	)
-	str = strings.Join(
-		[]string{str, fmt.Sprintf("-> %s : (%s)", fileInfo, source.Function)}, "\n")
+	fileInfo := " unknown source"
+	if source.File != "" {
+		fileInfo = fmt.Sprintf(
+			" \033[94m\033]8;;file://%s:%d\033\\%s:%d\033]8;;\033\\\033[0m",
+			source.AbsPath, source.Line, source.File, source.Line,
+		)
+	}
+	frame := fmt.Sprintf("-> %s : (%s)", fileInfo, source.Function)
+	if source.Inlined {
+		frame += " (inlined)"
+	}
+	if source.Disassembly != nil {
+		frame = fmt.Sprintf("%s [%s]", frame, source.Disassembly)
+	}
+	str = strings.Join([]string{str, frame}, "\n")
 	if source.CallStack != nil {
 		return printCallStack(source.CallStack, str)
 	}
@@ -0,0 +1,108 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// BuildConfig holds the build configuration of the analyzed program. It is applied
+// the same way when compiling the program and when loading its packages for SSA analysis.
+type BuildConfig struct {
+	Tags       []string // Build tags, passed as -tags.
+	LDFlags    string   // Linker flags, passed as -ldflags.
+	TrimPath   bool     // Remove file system paths from the binary, passed as -trimpath.
+	Mod        string   // Module download mode, passed as -mod.
+	CGOEnabled bool     // Value of CGO_ENABLED.
+	Env        []string // Extra environment variables in KEY=VALUE form.
+}
+
+// Flags returns the go build flags of the configuration.
+func (c *BuildConfig) Flags() []string {
+	flags := make([]string, 0)
+	if c == nil {
+		return flags
+	}
+	if len(c.Tags) > 0 {
+		flags = append(flags, "-tags="+strings.Join(c.Tags, ","))
+	}
+	if c.LDFlags != "" {
+		flags = append(flags, "-ldflags="+c.LDFlags)
+	}
+	if c.TrimPath {
+		flags = append(flags, "-trimpath")
+	}
+	if c.Mod != "" {
+		flags = append(flags, "-mod="+c.Mod)
+	}
+	return flags
+}
+
+// Environ returns the environment used to build the program for the given platform.
+// Extra variables of the configuration take precedence over the defaults.
+func (c *BuildConfig) Environ(goos, arch string) []string {
+	env := append(os.Environ(),
+		fmt.Sprintf("GOOS=%s", goos),
+		fmt.Sprintf("GOARCH=%s", arch),
+	)
+	if arch == "mips" || arch == "mipsle" {
+		env = append(env, "GOMIPS=softfloat")
+	}
+	if arch == "mips64" || arch == "mips64le" {
+		env = append(env, "GOMIPS64=softfloat")
+	}
+	if c == nil {
+		return env
+	}
+	if c.CGOEnabled {
+		env = append(env, "CGO_ENABLED=1")
+	} else {
+		env = append(env, "CGO_ENABLED=0")
+	}
+	return append(env, c.Env...)
+}
+
+// BuildBinary compiles the Go program at target for the given platform and writes the binary to output.
+func BuildBinary(target, goos, arch, output string, cfg *BuildConfig) error {
+	absPath, err := filepath.Abs(target)
+	if err != nil {
+		return err
+	}
+
+	// Find the module root of the target file
+	modRoot, err := FindGoModuleRoot(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to find go module root: %w", err)
+	}
+
+	args := append([]string{"build", "-o", output}, cfg.Flags()...)
+	//nolint:gosec
+	buildCmd := exec.Command("go", append(args, absPath)...)
+	buildCmd.Dir = modRoot // Set the working directory to the module root
+	buildCmd.Env = cfg.Environ(goos, arch)
+	if out, err := buildCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to build binary: %w\nOutput:\n%s", err, string(out))
+	}
+	return nil
+}
+
+// BuildTempBinary compiles target into a unique temporary directory, so concurrent runs never
+// share files. The returned cleanup function removes the directory.
+func BuildTempBinary(target, goos, arch string, cfg *BuildConfig) (string, func(), error) {
+	tempDir, err := os.MkdirTemp("", "vm-compat-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	cleanup := func() {
+		_ = os.RemoveAll(tempDir)
+	}
+
+	binary := filepath.Join(tempDir, "binary")
+	if err = BuildBinary(target, goos, arch, binary, cfg); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return binary, cleanup, nil
+}
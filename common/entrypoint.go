@@ -4,7 +4,7 @@ import "strings"
 
 func ProgramEntrypoint(arch string) func(function string) bool {
 	switch arch {
-	case "mips":
+	case "mips", "mipsle":
 		return func(function string) bool {
 			// Ignoring rt0_go directly as it contains unreachable portion
 			return function == "runtime.check" ||
@@ -17,7 +17,14 @@ func ProgramEntrypoint(arch string) func(function string) bool {
 				strings.Contains(function, ".init.") || // all init functions
 				strings.HasSuffix(function, ".init") // vars
 		}
-	case "mips64":
+	case "mips64", "mips64le":
+		return func(function string) bool {
+			return function == "runtime.rt0_go" || // start point of a go program
+				strings.Contains(function, "main.main") || // main and closures or anonymous functions
+				strings.Contains(function, ".init.") || // all init functions
+				strings.HasSuffix(function, ".init") // vars
+		}
+	case "riscv64":
 		return func(function string) bool {
 			return function == "runtime.rt0_go" || // start point of a go program
 				strings.Contains(function, "main.main") || // main and closures or anonymous functions
@@ -30,6 +30,14 @@ func (s *Stack[T]) Peek() (T, bool) {
 	return s.items[len(s.items)-1], true
 }
 
+// Last returns up to n items from the top of the stack, in the order they were pushed
+func (s *Stack[T]) Last(n int) []T {
+	if n > len(s.items) {
+		n = len(s.items)
+	}
+	return append([]T{}, s.items[len(s.items)-n:]...)
+}
+
 // Len returns the number of items in the stack
 func (s *Stack[T]) Len() int {
 	return len(s.items)
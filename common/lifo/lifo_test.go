@@ -117,6 +117,27 @@ func TestPeekEmpty(t *testing.T) {
 	}
 }
 
+// TestLast tests the Last() function
+func TestLast(t *testing.T) {
+	stack := Stack[int]{}
+	if last := stack.Last(2); len(last) != 0 {
+		t.Errorf("Expected no items from empty stack, got %v", last)
+	}
+	stack.Push(1)
+	stack.Push(2)
+	stack.Push(3)
+
+	if last := stack.Last(2); len(last) != 2 || last[0] != 2 || last[1] != 3 {
+		t.Errorf("Expected [2 3], got %v", last)
+	}
+	if last := stack.Last(5); len(last) != 3 || last[0] != 1 {
+		t.Errorf("Expected [1 2 3], got %v", last)
+	}
+	if stack.Len() != 3 {
+		t.Errorf("Expected Last to keep the items, got length %d", stack.Len())
+	}
+}
+
 // TestCopy tests the Copy() function
 func TestCopy(t *testing.T) {
 	original := Stack[int]{}
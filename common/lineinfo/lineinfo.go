@@ -0,0 +1,330 @@
+// Package lineinfo maps program counters of Go binaries to Go source positions.
+// It reads the Go line table (pclntab), which is kept even in stripped binaries,
+// and falls back to the DWARF line tables when the binary has them.
+package lineinfo
+
+import (
+	"bytes"
+	"debug/dwarf"
+	"debug/elf"
+	"debug/gosym"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Position is a location in Go source code.
+type Position struct {
+	File     string
+	Line     int
+	Function string
+	Inlined  bool // The function was inlined into its caller.
+}
+
+// Table resolves program counters to source positions.
+type Table struct {
+	pcln  *gosym.Table
+	dwarf *dwarf.Data
+	rows  []lineRow     // DWARF line rows sorted by address, built lazily
+	funcs []inlineRange // DWARF subprograms sorted by address, built lazily
+}
+
+// lineRow is a single row of a DWARF line table.
+type lineRow struct {
+	address uint64
+	file    string
+	line    int
+	end     bool // marks the first address after a sequence
+}
+
+// New creates a Table from the ELF binary.
+func New(binary []byte) (*Table, error) {
+	file, err := elf.NewFile(bytes.NewReader(binary))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read elf binary: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	table := &Table{}
+	if data, err := file.DWARF(); err == nil {
+		table.dwarf = data
+	}
+	if pcln, err := readPclntab(file); err == nil {
+		table.pcln = pcln
+	}
+	if table.pcln == nil && table.dwarf == nil {
+		return nil, errors.New("binary has neither a Go line table nor DWARF line information")
+	}
+	return table, nil
+}
+
+// Function is a function recorded in the Go line table.
+type Function struct {
+	Name  string
+	Entry uint64 // Address of the first instruction.
+	End   uint64 // Address after the last instruction.
+}
+
+// Functions returns the functions of the Go line table sorted by entry address. Unlike the
+// symbol table, the Go line table is kept in binaries linked with -s.
+func Functions(file *elf.File) ([]Function, error) {
+	table, err := readPclntab(file)
+	if err != nil {
+		return nil, err
+	}
+	funcs := make([]Function, 0, len(table.Funcs))
+	for _, fn := range table.Funcs {
+		funcs = append(funcs, Function{Name: fn.Name, Entry: fn.Entry, End: fn.End})
+	}
+	sort.SliceStable(funcs, func(i, j int) bool {
+		return funcs[i].Entry < funcs[j].Entry
+	})
+	return funcs, nil
+}
+
+// readPclntab loads the Go line table of the binary.
+func readPclntab(file *elf.File) (*gosym.Table, error) {
+	pclntab := file.Section(".gopclntab")
+	text := file.Section(".text")
+	if pclntab == nil || text == nil {
+		return nil, errors.New("binary has no .gopclntab section")
+	}
+	data, err := pclntab.Data()
+	if err != nil {
+		return nil, err
+	}
+	return gosym.NewTable(nil, gosym.NewLineTable(data, text.Addr))
+}
+
+// PCToLine returns the source position of the instruction at pc.
+func (t *Table) PCToLine(pc uint64) (*Position, bool) {
+	if t == nil {
+		return nil, false
+	}
+	if t.pcln != nil {
+		file, line, fn := t.pcln.PCToLine(pc)
+		if fn != nil && file != "" && line > 0 {
+			return &Position{File: file, Line: line, Function: fn.Name}, true
+		}
+	}
+	return t.dwarfPCToLine(pc)
+}
+
+// dwarfPCToLine looks up pc in the DWARF line tables.
+func (t *Table) dwarfPCToLine(pc uint64) (*Position, bool) {
+	if t.dwarf == nil {
+		return nil, false
+	}
+	if t.rows == nil {
+		t.rows = readLineRows(t.dwarf)
+	}
+	idx := sort.Search(len(t.rows), func(i int) bool {
+		return t.rows[i].address > pc
+	}) - 1
+	if idx < 0 || t.rows[idx].end || t.rows[idx].line <= 0 {
+		return nil, false
+	}
+	return &Position{File: t.rows[idx].file, Line: t.rows[idx].line}, true
+}
+
+// readLineRows collects the rows of every compilation unit line table.
+func readLineRows(data *dwarf.Data) []lineRow {
+	rows := make([]lineRow, 0)
+	reader := data.Reader()
+	for {
+		entry, err := reader.Next()
+		if err != nil || entry == nil {
+			break
+		}
+		if entry.Tag != dwarf.TagCompileUnit {
+			reader.SkipChildren()
+			continue
+		}
+		lr, err := data.LineReader(entry)
+		if err != nil || lr == nil {
+			continue
+		}
+		var le dwarf.LineEntry
+		for lr.Next(&le) == nil {
+			row := lineRow{address: le.Address, line: le.Line, end: le.EndSequence}
+			if le.File != nil {
+				row.file = le.File.Name
+			}
+			rows = append(rows, row)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].address < rows[j].address
+	})
+	return rows
+}
+
+// inlineNode is a function body in the DWARF inlining tree: a subprogram or an inlined subroutine.
+type inlineNode struct {
+	ranges   [][2]uint64
+	name     string
+	origin   dwarf.Offset // abstract origin holding the name of inlined subroutines
+	callFile string       // position of the call that was inlined
+	callLine int
+	children []*inlineNode
+}
+
+// contains reports whether pc is inside the body of the node.
+func (n *inlineNode) contains(pc uint64) bool {
+	for _, r := range n.ranges {
+		if pc >= r[0] && pc < r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// inlineRange is an address range of a subprogram, used to find the function of a pc.
+type inlineRange struct {
+	low, high uint64
+	node      *inlineNode
+}
+
+// Frames returns the source frames of the instruction at pc, innermost first. Functions inlined
+// at pc come first and are marked as inlined, the last frame is the function containing pc.
+// Inlined frames are read from the DWARF inlined subroutines, so binaries linked with -w only
+// get the containing function.
+func (t *Table) Frames(pc uint64) ([]*Position, bool) {
+	pos, ok := t.PCToLine(pc)
+	if !ok {
+		return nil, false
+	}
+	chain := t.inlineChain(pc)
+	if len(chain) == 0 {
+		return []*Position{pos}, true
+	}
+
+	// The line table reports the position in the innermost inlined body, and every inlined
+	// subroutine records where it was called from in the enclosing body.
+	frames := make([]*Position, 0, len(chain)+1)
+	file, line := pos.File, pos.Line
+	for i := len(chain) - 1; i >= 0; i-- {
+		frames = append(frames, &Position{File: file, Line: line, Function: chain[i].name, Inlined: true})
+		file, line = chain[i].callFile, chain[i].callLine
+	}
+	return append(frames, &Position{File: file, Line: line, Function: pos.Function}), true
+}
+
+// inlineChain returns the inlined subroutines containing pc, outermost first.
+func (t *Table) inlineChain(pc uint64) []*inlineNode {
+	if t.dwarf == nil {
+		return nil
+	}
+	if t.funcs == nil {
+		t.funcs = readInlineTree(t.dwarf)
+	}
+	idx := sort.Search(len(t.funcs), func(i int) bool {
+		return t.funcs[i].low > pc
+	}) - 1
+	if idx < 0 || pc >= t.funcs[idx].high {
+		return nil
+	}
+
+	chain := make([]*inlineNode, 0)
+	node := t.funcs[idx].node
+	for {
+		var next *inlineNode
+		for _, child := range node.children {
+			if child.contains(pc) {
+				next = child
+				break
+			}
+		}
+		if next == nil || next.name == "" {
+			return chain
+		}
+		chain = append(chain, next)
+		node = next
+	}
+}
+
+// readInlineTree collects the concrete subprograms of the DWARF data together with
+// the subroutines inlined into them, sorted by address.
+//
+//nolint:cyclop
+func readInlineTree(data *dwarf.Data) []inlineRange {
+	funcs := make([]inlineRange, 0)
+	names := make(map[dwarf.Offset]string)
+	inlined := make([]*inlineNode, 0)
+
+	var files []*dwarf.LineFile
+	// Nodes enclosing the current entry, one per open level of children
+	stack := make([]*inlineNode, 0)
+	current := func() *inlineNode {
+		if len(stack) == 0 {
+			return nil
+		}
+		return stack[len(stack)-1]
+	}
+
+	reader := data.Reader()
+	for {
+		entry, err := reader.Next()
+		if err != nil || entry == nil {
+			break
+		}
+		if entry.Tag == 0 {
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+		if name, ok := entry.Val(dwarf.AttrName).(string); ok {
+			names[entry.Offset] = name
+		}
+
+		node := current()
+		switch entry.Tag {
+		case dwarf.TagCompileUnit:
+			files = nil
+			if lr, err := data.LineReader(entry); err == nil && lr != nil {
+				files = lr.Files()
+			}
+			node = nil
+		case dwarf.TagSubprogram:
+			node = nil
+			if ranges, err := data.Ranges(entry); err == nil && len(ranges) > 0 {
+				node = &inlineNode{ranges: ranges}
+				for _, r := range ranges {
+					funcs = append(funcs, inlineRange{low: r[0], high: r[1], node: node})
+				}
+			}
+		case dwarf.TagInlinedSubroutine:
+			ranges, err := data.Ranges(entry)
+			if node == nil || err != nil {
+				node = nil
+				break
+			}
+			child := &inlineNode{ranges: ranges}
+			child.origin, _ = entry.Val(dwarf.AttrAbstractOrigin).(dwarf.Offset)
+			if idx, ok := entry.Val(dwarf.AttrCallFile).(int64); ok && idx >= 0 && int(idx) < len(files) && files[idx] != nil {
+				child.callFile = files[idx].Name
+			}
+			if line, ok := entry.Val(dwarf.AttrCallLine).(int64); ok {
+				child.callLine = int(line)
+			}
+			node.children = append(node.children, child)
+			inlined = append(inlined, child)
+			node = child
+		}
+		if entry.Children {
+			stack = append(stack, node)
+		}
+	}
+
+	// Abstract origins may follow the inlined subroutines referring to them
+	for _, node := range inlined {
+		node.name = names[node.origin]
+	}
+	sort.SliceStable(funcs, func(i, j int) bool {
+		return funcs[i].low < funcs[j].low
+	})
+	return funcs
+}
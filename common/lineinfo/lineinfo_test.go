@@ -0,0 +1,116 @@
+package lineinfo_test
+
+import (
+	"debug/elf"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ChainSafe/vm-compat/common"
+	"github.com/ChainSafe/vm-compat/common/lineinfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPCToLine(t *testing.T) {
+	for name, cfg := range map[string]*common.BuildConfig{
+		"default":  nil,
+		"stripped": {LDFlags: "-s -w"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			binary := filepath.Join(t.TempDir(), "sample")
+			require.NoError(t, common.BuildBinary("../../examples/sample.go", "linux", "mips64", binary, cfg))
+
+			data, err := os.ReadFile(binary)
+			require.NoError(t, err)
+			table, err := lineinfo.New(data)
+			require.NoError(t, err)
+
+			pc := mainAddress(t, binary)
+			pos, ok := table.PCToLine(pc)
+			require.True(t, ok)
+			assert.Equal(t, "main.main", pos.Function)
+			assert.Equal(t, "sample.go", filepath.Base(pos.File))
+			assert.Positive(t, pos.Line)
+
+			_, ok = table.PCToLine(0)
+			assert.False(t, ok)
+		})
+	}
+}
+
+// mainAddress returns the entry of main.main. Stripped binaries have no symbol table,
+// so the entry is then searched in the Go line table.
+func mainAddress(t *testing.T, binary string) uint64 {
+	t.Helper()
+	file, err := elf.Open(binary)
+	require.NoError(t, err)
+	defer func() {
+		_ = file.Close()
+	}()
+
+	symbols, err := file.Symbols()
+	if err == nil {
+		for _, sym := range symbols {
+			if sym.Name == "main.main" {
+				return sym.Value
+			}
+		}
+	}
+	text := file.Section(".text")
+	require.NotNil(t, text)
+	data, err := os.ReadFile(binary)
+	require.NoError(t, err)
+	table, err := lineinfo.New(data)
+	require.NoError(t, err)
+	for pc := text.Addr; pc < text.Addr+text.Size; pc += 4 {
+		if pos, ok := table.PCToLine(pc); ok && pos.Function == "main.main" {
+			return pc
+		}
+	}
+	t.Fatal("main.main not found")
+	return 0
+}
+
+func TestFramesInlined(t *testing.T) {
+	binary := filepath.Join(t.TempDir(), "sample")
+	require.NoError(t, common.BuildBinary("../../examples/sample.go", "linux", "mips64", binary, nil))
+
+	data, err := os.ReadFile(binary)
+	require.NoError(t, err)
+	table, err := lineinfo.New(data)
+	require.NoError(t, err)
+
+	file, err := elf.Open(binary)
+	require.NoError(t, err)
+	defer func() {
+		_ = file.Close()
+	}()
+	symbols, err := file.Symbols()
+	require.NoError(t, err)
+
+	// os.(*File).Write inlines os.(*File).write
+	var write elf.Symbol
+	for _, sym := range symbols {
+		if sym.Name == "os.(*File).Write" {
+			write = sym
+		}
+	}
+	require.NotZero(t, write.Value)
+
+	found := false
+	for pc := write.Value; pc < write.Value+write.Size && !found; pc += 4 {
+		frames, ok := table.Frames(pc)
+		require.True(t, ok)
+		outer := frames[len(frames)-1]
+		assert.Equal(t, "os.(*File).Write", outer.Function)
+		assert.False(t, outer.Inlined)
+		if frames[0].Function == "os.(*File).write" {
+			assert.True(t, frames[0].Inlined)
+			assert.Equal(t, "file_posix.go", filepath.Base(frames[0].File))
+			assert.Equal(t, "file.go", filepath.Base(outer.File))
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
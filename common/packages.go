@@ -0,0 +1,77 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// MainPackage describes a main package that can be built into a binary.
+type MainPackage struct {
+	ImportPath string // Import path of the package, "command-line-arguments" for source files.
+	Dir        string // Directory containing the package.
+}
+
+// PackagePattern converts target into a pattern understood by the go command together with the
+// directory it must run in. The target may be a Go source file, a package directory, a module
+// directory (all packages of the module) or a package pattern such as ./cmd/...
+func PackagePattern(target string) (dir, pattern string, err error) {
+	// Import path patterns are resolved by the go command from the working directory.
+	if !filepath.IsAbs(target) && !strings.HasPrefix(target, ".") {
+		if _, err := os.Stat(target); err != nil {
+			return "", target, nil
+		}
+	}
+	pattern, err = filepath.Abs(target)
+	if err != nil {
+		return "", "", err
+	}
+	if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+		if _, err := os.Stat(filepath.Join(pattern, "go.mod")); err == nil {
+			pattern = filepath.Join(pattern, "...")
+		}
+	}
+	dir, err = FindGoModuleRoot(pattern)
+	if err != nil {
+		return "", "", err
+	}
+	return dir, pattern, nil
+}
+
+// FindMainPackages returns every main package matching target, see PackagePattern.
+func FindMainPackages(target, goos, arch string, cfg *BuildConfig) ([]*MainPackage, error) {
+	dir, pattern, err := PackagePattern(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve package pattern: %w", err)
+	}
+	pkgs, err := packages.Load(&packages.Config{
+		Mode:       packages.NeedName | packages.NeedFiles,
+		BuildFlags: cfg.Flags(),
+		Dir:        dir,
+		Env:        cfg.Environ(goos, arch),
+	}, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+
+	mains := make([]*MainPackage, 0)
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			return nil, fmt.Errorf("failed to load package %s: %v", pkg.PkgPath, pkg.Errors[0])
+		}
+		if pkg.Name != "main" || len(pkg.GoFiles) == 0 {
+			continue
+		}
+		mains = append(mains, &MainPackage{
+			ImportPath: pkg.PkgPath,
+			Dir:        filepath.Dir(pkg.GoFiles[0]),
+		})
+	}
+	if len(mains) == 0 {
+		return nil, fmt.Errorf("no main packages found in %s", target)
+	}
+	return mains, nil
+}
@@ -4,16 +4,28 @@ import (
 	"fmt"
 	"path/filepath"
 	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/ChainSafe/vm-compat/analyzer"
 	"github.com/ChainSafe/vm-compat/asmparser"
+	"github.com/ChainSafe/vm-compat/common/lineinfo"
 )
 
 // TraceAsmCaller correctly tracks function calls in the execution stack.
+// The trace starts at instr, or at the start of function when instr is nil, and every
+// caller frame points at its call site. Frames are mapped to Go source lines through
+// lines when available, and through the source positions of the disassembly otherwise.
+// Frames also point at the lines of the text disassembly stored at disassemblyPath, if set.
+// A path made of direct calls is preferred over one through resolved indirect calls, whose
+// candidate targets over-approximate the callers, and parents are visited in address order
+// so that the same trace is found on every run.
 func TraceAsmCaller(
-	filePath string,
+	disassemblyPath string,
 	graph asmparser.CallGraph,
 	function string,
+	instr asmparser.Instruction,
+	lines *lineinfo.Table,
 	endCond func(string) bool,
 ) (*analyzer.CallStack, error) {
 	var segment asmparser.Segment
@@ -24,28 +36,30 @@ func TraceAsmCaller(
 		}
 	}
 	if segment == nil {
-		return nil, fmt.Errorf("could not find %s in %s", function, filePath)
+		return nil, fmt.Errorf("could not find %s in the disassembly", function)
 	}
-	seen := make(map[asmparser.Segment]bool)
-	var visit func(graph asmparser.CallGraph, segment asmparser.Segment) *analyzer.CallStack
+	indirect := make(map[asmparser.Instruction]bool)
+	for _, call := range graph.IndirectCalls() {
+		indirect[call.Instruction] = true
+	}
+	var seen map[asmparser.Segment]bool
+	var visit func(graph asmparser.CallGraph, segment asmparser.Segment, at asmparser.Instruction, directOnly bool) *analyzer.CallStack
 
-	visit = func(graph asmparser.CallGraph, segment asmparser.Segment) *analyzer.CallStack {
+	visit = func(graph asmparser.CallGraph, segment asmparser.Segment, at asmparser.Instruction, directOnly bool) *analyzer.CallStack {
 		if seen[segment] {
 			return nil
 		}
 		seen[segment] = true
 
-		source := &analyzer.CallStack{
-			File:     filepath.Base(filePath),
-			Line:     segment.Instructions()[0].Line() - 1, // function start line
-			AbsPath:  filePath,
-			Function: segment.Label(),
-		}
-		if endCond(source.Function) {
+		source := asmFrame(disassemblyPath, segment, at, lines)
+		if endCond(segment.Label()) {
 			return source
 		}
-		for _, seg := range graph.ParentsOf(segment) {
-			ch := visit(graph, seg)
+		for _, call := range callersOf(graph, segment, indirect) {
+			if directOnly && call.indirect {
+				continue
+			}
+			ch := visit(graph, call.parent, call.site, directOnly)
 			if ch != nil {
 				source.AddCallStack(ch)
 				return source
@@ -53,11 +67,119 @@ func TraceAsmCaller(
 		}
 		return nil
 	}
-	src := visit(graph, segment)
-	if src == nil {
-		return nil, fmt.Errorf("no trace found to root for the given function")
+	for _, directOnly := range []bool{true, false} {
+		seen = make(map[asmparser.Segment]bool)
+		if src := visit(graph, segment, instr, directOnly); src != nil {
+			return src, nil
+		}
+	}
+	return nil, fmt.Errorf("no trace found to root for the given function")
+}
+
+// callEdge is a parent segment with the call site the trace goes through.
+type callEdge struct {
+	parent   asmparser.Segment
+	site     asmparser.Instruction
+	indirect bool // The parent only calls through indirect calls.
+}
+
+// callersOf returns the parents of segment in address order, those with a direct call site first.
+// The site of every parent is its first direct call of segment, or its first indirect one.
+func callersOf(graph asmparser.CallGraph, segment asmparser.Segment, indirect map[asmparser.Instruction]bool) []*callEdge {
+	direct := make([]*callEdge, 0)
+	others := make([]*callEdge, 0)
+	for _, seg := range graph.ParentsOf(segment) {
+		sites := graph.CallSites(seg, segment)
+		if len(sites) == 0 {
+			direct = append(direct, &callEdge{parent: seg})
+			continue
+		}
+		if i := slices.IndexFunc(sites, func(site asmparser.Instruction) bool { return !indirect[site] }); i >= 0 {
+			direct = append(direct, &callEdge{parent: seg, site: sites[i]})
+			continue
+		}
+		others = append(others, &callEdge{parent: seg, site: sites[0], indirect: true})
+	}
+	return append(direct, others...)
+}
+
+// asmFrame creates the call stack frames of instruction at in segment. Functions inlined
+// at the instruction come first as inlined frames, followed by the frame of the segment.
+// When at is nil the frames point at the start of the segment. Frames without a Go source
+// position only carry the function and the address.
+func asmFrame(disassemblyPath string, segment asmparser.Segment, at asmparser.Instruction, lines *lineinfo.Table) *analyzer.CallStack {
+	line := 0
+	address := segment.Address()
+	if at != nil {
+		line = at.Line()
+		address = at.Address()
+	} else if instrs := segment.Instructions(); len(instrs) > 0 {
+		at = instrs[0]
+		line = at.Line() - 1 // function start line
+	}
+
+	source := &analyzer.CallStack{
+		Function:    segment.Label(),
+		Disassembly: &analyzer.DisassemblyLocation{Address: address},
+	}
+	// The lines of the instructions only exist in a stored text disassembly
+	if disassemblyPath != "" {
+		source.Disassembly.File = disassemblyPath
+		source.Disassembly.Line = line
+	}
+	// Prefer the Go source position from the binary line tables, then the one of the disassembly
+	if pc, err := strconv.ParseUint(strings.TrimPrefix(address, "0x"), 16, 64); err == nil {
+		if frames, ok := lines.Frames(pc); ok {
+			var head *analyzer.CallStack
+			for _, pos := range frames {
+				frame := source.Copy()
+				frame.File = filepath.Base(pos.File)
+				frame.Line = pos.Line
+				frame.AbsPath = pos.File
+				if pos.Inlined {
+					frame.Function = pos.Function
+					frame.Inlined = true
+				}
+				if head == nil {
+					head = frame
+				} else {
+					head.AddCallStack(frame)
+				}
+			}
+			return head
+		}
+	}
+	if at != nil {
+		if pos := at.Source(); pos != nil {
+			source.File = filepath.Base(pos.File)
+			source.Line = pos.Line
+			source.AbsPath = pos.File
+		}
+	}
+	return source
+}
+
+// DisassemblyPath returns the absolute path of the text disassembly of the program, or an
+// empty path when the disassembly is not stored in a file.
+func DisassemblyPath(program *analyzer.Program) (string, error) {
+	if program.DisassemblyPath == "" {
+		return "", nil
+	}
+	return filepath.Abs(program.DisassemblyPath)
+}
+
+// ProgramLines returns the line table of the program binary. It returns nil when the program
+// has no binary or the binary carries no line information, call stacks then fall back to
+// the source positions of the disassembly.
+func ProgramLines(program *analyzer.Program) *lineinfo.Table {
+	if len(program.Binary) == 0 {
+		return nil
+	}
+	lines, err := lineinfo.New(program.Binary)
+	if err != nil {
+		return nil
 	}
-	return src, nil
+	return lines
 }
 
 func ShouldIgnoreSource(callStack *analyzer.CallStack, functions []string) bool {
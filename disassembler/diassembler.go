@@ -1,6 +1,8 @@
 // Package disassembler provides a way to disassemble binaries and source code.
 package disassembler
 
+import "io"
+
 type Source int64
 
 const (
@@ -9,11 +11,14 @@ const (
 )
 
 type Disassembler interface {
-	Disassemble(mode Source, target string, outputPath string) (string, error)
+	// Disassemble disassembles the target and returns the output consumed by an asmparser.Parser.
+	Disassemble(mode Source, target string) (io.Reader, error)
 }
 
 type Type int64
 
 const (
 	TypeObjdump Type = iota + 1
+	TypeNative
+	TypeGoObjdump
 )
@@ -0,0 +1,72 @@
+// Package goobjdump provides a disassembler backed by `go tool objdump`.
+// Its output annotates every instruction with the Go source position it was generated from.
+package goobjdump
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/ChainSafe/vm-compat/common"
+	"github.com/ChainSafe/vm-compat/disassembler"
+)
+
+type GoObjdump struct {
+	Arch  string
+	GOOS  string
+	Build *common.BuildConfig
+}
+
+func New(goos, arch string, build *common.BuildConfig) *GoObjdump {
+	return &GoObjdump{
+		Arch:  arch,
+		GOOS:  goos,
+		Build: build,
+	}
+}
+
+func (o *GoObjdump) Disassemble(mode disassembler.Source, target string) (io.Reader, error) {
+	var disassembly []byte
+	var err error
+
+	switch mode {
+	case disassembler.SourceBinary:
+		disassembly, err = objdump(target)
+	case disassembler.SourceFile:
+		disassembly, err = generateSourceAssembly(target, o.GOOS, o.Arch, o.Build)
+	default:
+		return nil, fmt.Errorf("unsupported source mode: %d", mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(disassembly), nil
+}
+
+func generateSourceAssembly(target string, goos, arch string, build *common.BuildConfig) ([]byte, error) {
+	binary, cleanup, err := common.BuildTempBinary(target, goos, arch, build)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	return objdump(binary)
+}
+
+// objdump runs `go tool objdump` on the binary.
+// Note: the Go toolchain can only disassemble the architectures supported by cmd/objdump.
+func objdump(binary string) ([]byte, error) {
+	//nolint:gosec
+	cmd := exec.Command("go", "tool", "objdump", binary)
+	output, err := cmd.Output()
+	if err != nil {
+		var stderr []byte
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			stderr = exitErr.Stderr
+		}
+		return nil, fmt.Errorf("failed to generate disassembly: %w\nOutput:\n%s", err, string(stderr))
+	}
+	return output, nil
+}
@@ -3,15 +3,28 @@ package manager
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/ChainSafe/vm-compat/common"
 	"github.com/ChainSafe/vm-compat/disassembler"
+	"github.com/ChainSafe/vm-compat/disassembler/goobjdump"
+	"github.com/ChainSafe/vm-compat/disassembler/native"
 	"github.com/ChainSafe/vm-compat/disassembler/objdump"
 )
 
-func NewDisassembler(typ disassembler.Type, os, arch string) (disassembler.Disassembler, error) {
+func NewDisassembler(typ disassembler.Type, os, arch string, build *common.BuildConfig) (disassembler.Disassembler, error) {
 	switch typ {
 	case disassembler.TypeObjdump:
-		return objdump.New(os, arch), nil
+		return objdump.New(os, arch, build), nil
+	case disassembler.TypeNative:
+		return native.New(os, arch, build), nil
+	case disassembler.TypeGoObjdump:
+		// cmd/objdump has no MIPS disassembler
+		if strings.HasPrefix(arch, "mips") {
+			return nil, fmt.Errorf("go tool objdump cannot disassemble %s binaries, use the native or objdump disassembler", arch)
+		}
+		return goobjdump.New(os, arch, build), nil
 	default:
 		return nil, errors.New("disassembler not supported")
 	}
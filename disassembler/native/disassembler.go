@@ -0,0 +1,97 @@
+// Package native provides a pure Go disassembler that reads ELF binaries directly,
+// without relying on external tools such as llvm-objdump.
+package native
+
+import (
+	"bytes"
+	"debug/elf"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ChainSafe/vm-compat/common"
+	"github.com/ChainSafe/vm-compat/disassembler"
+)
+
+type Native struct {
+	Arch  string
+	GOOS  string
+	Build *common.BuildConfig
+}
+
+func New(goos, arch string, build *common.BuildConfig) *Native {
+	return &Native{
+		Arch:  arch,
+		GOOS:  goos,
+		Build: build,
+	}
+}
+
+// Disassemble returns the ELF binary of target. The binary is decoded by the
+// asmparser directly from its .text section and symbol table.
+func (n *Native) Disassemble(mode disassembler.Source, target string) (io.Reader, error) {
+	var binary []byte
+	var err error
+
+	switch mode {
+	case disassembler.SourceBinary:
+		binary, err = os.ReadFile(target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read binary: %w", err)
+		}
+	case disassembler.SourceFile:
+		binary, err = buildBinary(target, n.GOOS, n.Arch, n.Build)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported source mode: %d", mode)
+	}
+	if err = checkArch(binary, n.Arch); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(binary), nil
+}
+
+func buildBinary(target string, goos, arch string, build *common.BuildConfig) ([]byte, error) {
+	path, cleanup, err := common.BuildTempBinary(target, goos, arch, build)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	binary, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read binary: %w", err)
+	}
+	return binary, nil
+}
+
+// checkArch verifies that the ELF binary was built for the given GOARCH.
+func checkArch(binary []byte, arch string) error {
+	file, err := elf.NewFile(bytes.NewReader(binary))
+	if err != nil {
+		return fmt.Errorf("failed to read elf binary: %w", err)
+	}
+	machine := elf.EM_MIPS
+	var class elf.Class
+	var data elf.Data
+	switch arch {
+	case "mips":
+		class, data = elf.ELFCLASS32, elf.ELFDATA2MSB
+	case "mipsle":
+		class, data = elf.ELFCLASS32, elf.ELFDATA2LSB
+	case "mips64":
+		class, data = elf.ELFCLASS64, elf.ELFDATA2MSB
+	case "mips64le":
+		class, data = elf.ELFCLASS64, elf.ELFDATA2LSB
+	case "riscv64":
+		machine, class, data = elf.EM_RISCV, elf.ELFCLASS64, elf.ELFDATA2LSB
+	default:
+		return fmt.Errorf("unsupported GOARCH: %s", arch)
+	}
+	if file.Machine != machine || file.Class != class || file.Data != data {
+		return fmt.Errorf("binary built for %s %s %s does not match GOARCH %s", file.Machine, file.Class, file.Data, arch)
+	}
+	return nil
+}
@@ -2,112 +2,125 @@
 package objdump
 
 import (
+	"bufio"
+	"bytes"
+	"debug/elf"
 	"fmt"
-	"os"
+	"io"
 	"os/exec"
-	"path/filepath"
+	"regexp"
+	"strconv"
 
+	"github.com/ChainSafe/vm-compat/asmparser/elftext"
 	"github.com/ChainSafe/vm-compat/common"
 	"github.com/ChainSafe/vm-compat/disassembler"
 )
 
+var (
+	// addressRegex matches the address of an instruction line of llvm-objdump.
+	addressRegex = regexp.MustCompile(`^\s*([0-9a-fA-F]+):\s`)
+	// labelRegex matches the line labeling the function at an address.
+	labelRegex = regexp.MustCompile(`^([0-9a-fA-F]+) <([^>]+)>:$`)
+)
+
 type Objdump struct {
-	Arch string
-	GOOS string
+	Arch  string
+	GOOS  string
+	Build *common.BuildConfig
 }
 
-func New(goos, arch string) *Objdump {
+func New(goos, arch string, build *common.BuildConfig) *Objdump {
 	return &Objdump{
-		Arch: arch,
-		GOOS: goos,
+		Arch:  arch,
+		GOOS:  goos,
+		Build: build,
 	}
 }
 
-func (o *Objdump) Disassemble(mode disassembler.Source, target string, outputPath string) (string, error) {
-	var disassembly string
+func (o *Objdump) Disassemble(mode disassembler.Source, target string) (io.Reader, error) {
+	var disassembly []byte
 	var err error
 
 	switch mode {
 	case disassembler.SourceBinary:
 		disassembly, err = generateBinaryDisassembly(target)
-		if err != nil {
-			return "", err
-		}
 	case disassembler.SourceFile:
-		disassembly, err = generateSourceAssembly(target, o.GOOS, o.Arch)
-		if err != nil {
-			return "", err
-		}
+		disassembly, err = generateSourceAssembly(target, o.GOOS, o.Arch, o.Build)
+	default:
+		return nil, fmt.Errorf("unsupported source mode: %d", mode)
 	}
-
-	if outputPath != "" {
-		absOutputPath, err := filepath.Abs(outputPath)
-		if err != nil {
-			return "", fmt.Errorf("failed to get absolute path of output file: %w", err)
-		}
-		err = os.WriteFile(absOutputPath, []byte(disassembly), 0600)
-		if err != nil {
-			return "", fmt.Errorf("failed to write to output file: %w", err)
-		}
-		return fmt.Sprintf("disassembly written to %s", outputPath), nil
+	if err != nil {
+		return nil, err
 	}
-	return disassembly, nil
+	return bytes.NewReader(disassembly), nil
 }
 
-func generateSourceAssembly(target string, goos, arch string) (string, error) {
-	// Build the binary
-	tempFile := filepath.Join(os.TempDir(), "temp_binary")
-	defer func() {
-		_ = os.Remove(tempFile)
-	}()
-
-	absPath, err := filepath.Abs(target)
+func generateSourceAssembly(target string, goos, arch string, build *common.BuildConfig) ([]byte, error) {
+	binary, cleanup, err := common.BuildTempBinary(target, goos, arch, build)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	defer cleanup()
 
-	// Find the module root of the target file
-	modRoot, err := common.FindGoModuleRoot(absPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to find go module root: %w", err)
-	}
+	return generateBinaryDisassembly(binary)
+}
 
+func generateBinaryDisassembly(target string) ([]byte, error) {
+	// Run objdump on the binary
+	objdumpCmd := exec.Command("llvm-objdump", "-d", target)
 	//nolint:gosec
-	buildCmd := exec.Command("go", "build", "-o", tempFile, absPath)
-	buildCmd.Dir = modRoot // Set the working directory to the module root
-	buildCmd.Env = append(os.Environ(),
-		fmt.Sprintf("GOOS=%s", goos),
-		fmt.Sprintf("GOARCH=%s", arch),
-	)
-	if arch == "mips" {
-		buildCmd.Env = append(buildCmd.Env, "GOMIPS=softfloat")
-	}
-	if arch == "mips64" {
-		buildCmd.Env = append(buildCmd.Env, "GOMIPS64=softfloat")
-	}
-	var output []byte
-	if output, err = buildCmd.CombinedOutput(); err != nil {
-		return "", fmt.Errorf("failed to build binary: %w\nOutput:\n%s", err, string(output))
+	output, err := objdumpCmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate binary disassembly: %w\nOutput:\n%s", err, string(output))
 	}
 
-	// Generate assembly output
-	//nolint:gosec
-	cmd := exec.Command("llvm-objdump", "-d", tempFile)
-	output, err = cmd.CombinedOutput()
+	return labelFunctions(target, output)
+}
+
+// labelFunctions names the functions of the disassembly after the function symbols of the binary.
+// llvm-objdump labels an address with one of its symbols, which may be a marker without size such
+// as runtime.text, so the label is replaced by the symbol of the function at that address.
+// When the binary has no symbol table, llvm-objdump labels the whole text section as a single
+// block, and a label line is added at the entry of every function instead. The functions are
+// then taken from the Go line table, which is kept in stripped binaries.
+func labelFunctions(target string, disassembly []byte) ([]byte, error) {
+	file, err := elf.Open(target)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate source assembly: %w\nOutput:\n%s", err, string(output))
+		return nil, fmt.Errorf("failed to read elf binary: %w", err)
 	}
-	return string(output), nil
-}
+	defer func() {
+		_ = file.Close()
+	}()
 
-func generateBinaryDisassembly(target string) (string, error) {
-	// Run objdump on the binary
-	objdumpCmd := exec.Command("llvm-objdump", "-d", target)
-	//nolint:gosec
-	output, err := objdumpCmd.CombinedOutput()
+	labels, stripped, err := elftext.Labels(file)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate binary disassembly: %w\nOutput:\n%s", err, string(output))
+		return nil, err
+	}
+	width := 16
+	if file.Class == elf.ELFCLASS32 {
+		width = 8
 	}
 
-	return string(output), nil
+	var labeled bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(disassembly))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if matches := labelRegex.FindStringSubmatch(line); matches != nil && !stripped {
+			address, err := strconv.ParseUint(matches[1], 16, 64)
+			if err == nil && labels[address] != "" {
+				line = fmt.Sprintf("%s <%s>:", matches[1], labels[address])
+			}
+		} else if matches := addressRegex.FindStringSubmatch(line); matches != nil && stripped {
+			address, err := strconv.ParseUint(matches[1], 16, 64)
+			if err == nil && labels[address] != "" {
+				labeled.WriteString(fmt.Sprintf("\n%0*x <%s>:\n", width, address, labels[address]))
+			}
+		}
+		labeled.WriteString(line)
+		labeled.WriteByte('\n')
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read disassembly: %w", err)
+	}
+	return labeled.Bytes(), nil
 }
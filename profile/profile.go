@@ -9,9 +9,44 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// OpcodeInstruction describes the variants of an opcode allowed by the VM, either by their encoding
+// or by the Mnemonic of a single operation (e.g. mul or ll).
+// An empty Funct allows only instructions without a function code, while an
+// empty sub-field list (RT, Fmt, SA) allows any value of that sub-field.
 type OpcodeInstruction struct {
-	Opcode string   `yaml:"opcode"`
-	Funct  []string `yaml:"funct"`
+	Mnemonic string   `yaml:"mnemonic,omitempty"`
+	Opcode   string   `yaml:"opcode,omitempty"`
+	Funct    []string `yaml:"funct,omitempty"`
+	RT       []string `yaml:"rt,omitempty"`  // rt field of MIPS REGIMM (0x1), and the tf bit of COP1 branches
+	Fmt      []string `yaml:"fmt,omitempty"` // fmt field of MIPS COP1 (0x11)
+	SA       []string `yaml:"sa,omitempty"`  // sa field of MIPS SPECIAL3 bshfl and dbshfl (0x1f)
+}
+
+// Fields returns the sub-field constraints keyed by the names reported by asmparser.Instruction.Fields.
+func (o OpcodeInstruction) Fields() map[string][]string {
+	return map[string][]string{
+		"rt":  o.RT,
+		"fmt": o.Fmt,
+		"sa":  o.SA,
+	}
+}
+
+// MaxContextDepth bounds the ContextDepth of a profile.
+const MaxContextDepth = 8
+
+// Algorithms building the call graph of the SSA analysis.
+const (
+	CallGraphCHA = "cha" // Class hierarchy analysis: every method of a matching type, reachable or not.
+	CallGraphRTA = "rta" // Rapid type analysis: methods of the types made reachable from the roots.
+	CallGraphVTA = "vta" // Variable type analysis: the types flowing to each call, refining CHA.
+)
+
+// SyscallAPI is a function making syscalls for the SSA analysis, whose number is passed in the
+// argument at NumberArg. Functions are named as in the SSA form, e.g. syscall.Syscall or
+// (*example.com/pkg.T).Syscall, and the receiver of a method is its first argument.
+type SyscallAPI struct {
+	Function  string `yaml:"function"`
+	NumberArg int    `yaml:"number_arg"`
 }
 
 // VMProfile represents the configuration for a specific VM.
@@ -23,6 +58,11 @@ type VMProfile struct {
 	AllowedSycalls   []int               `yaml:"allowed_syscalls"`
 	NOOPSyscalls     []int               `yaml:"noop_syscalls"`
 	IgnoredFunctions []string            `yaml:"ignored_functions"`
+	CallGraph        string              `yaml:"call_graph"`   // Call graph algorithm of the SSA analysis: cha, rta or vta.
+	SyscallAPIs      []SyscallAPI        `yaml:"syscall_apis"` // Added to the built-in syscall APIs of the SSA analysis.
+	// ContextDepth is the number of calls telling apart the contexts a function is analyzed in by the
+	// SSA analysis, 2 when unset.
+	ContextDepth int `yaml:"context_depth"`
 }
 
 func (p *VMProfile) SetDefaults() {
@@ -53,5 +93,48 @@ func LoadProfile(filename string) (*VMProfile, error) {
 	if err = yaml.NewDecoder(file).Decode(&profile); err != nil {
 		return nil, fmt.Errorf("failed to parse profile: %w", err)
 	}
+	if err = profile.validate(); err != nil {
+		return nil, fmt.Errorf("invalid profile: %w", err)
+	}
 	return &profile, nil
 }
+
+// ValidateCallGraph checks that algorithm is a call graph algorithm of the SSA analysis, or empty
+// for the default one.
+func ValidateCallGraph(algorithm string) error {
+	switch algorithm {
+	case "", CallGraphCHA, CallGraphRTA, CallGraphVTA:
+		return nil
+	}
+	return fmt.Errorf("invalid call graph algorithm %q, options: %s, %s, %s", algorithm, CallGraphCHA, CallGraphRTA, CallGraphVTA)
+}
+
+// validate checks that every allowed opcode is given either by mnemonic or by encoding, that
+// every syscall API names a function and an argument, and that the call graph algorithm and the
+// context depth are valid.
+func (p *VMProfile) validate() error {
+	if err := ValidateCallGraph(p.CallGraph); err != nil {
+		return fmt.Errorf("call_graph: %w", err)
+	}
+	if p.ContextDepth < 0 || p.ContextDepth > MaxContextDepth {
+		return fmt.Errorf("context_depth %d is not between 0 and %d", p.ContextDepth, MaxContextDepth)
+	}
+	for i, api := range p.SyscallAPIs {
+		switch {
+		case api.Function == "":
+			return fmt.Errorf("syscall api %d has no function", i)
+		case api.NumberArg < 0:
+			return fmt.Errorf("syscall api %q: negative number_arg %d", api.Function, api.NumberArg)
+		}
+	}
+	for i, instr := range p.AllowedOpcodes {
+		switch {
+		case instr.Mnemonic == "" && instr.Opcode == "":
+			return fmt.Errorf("allowed opcode %d has neither mnemonic nor opcode", i)
+		case instr.Mnemonic != "" && (instr.Opcode != "" || len(instr.Funct) > 0 ||
+			len(instr.RT) > 0 || len(instr.Fmt) > 0 || len(instr.SA) > 0):
+			return fmt.Errorf("allowed opcode %q: mnemonic cannot be combined with encoding fields", instr.Mnemonic)
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -13,6 +14,12 @@ import (
 	"github.com/ChainSafe/vm-compat/profile"
 )
 
+// issueGroup identifies issues reported together.
+type issueGroup struct {
+	binary  string
+	message string
+}
+
 // TextRenderer formats the analysis report in a structured text format.
 type TextRenderer struct {
 	profile *profile.VMProfile
@@ -31,23 +38,35 @@ func (r *TextRenderer) Render(issues []*analyzer.Issue, output io.Writer) error
 
 	timestamp := time.Now().Format("2006-01-02 15:04:05 UTC")
 
-	// Group issues by message
-	groupedIssues := make(map[string][]*analyzer.Issue)
+	// Group issues by binary and message
+	groupedIssues := make(map[issueGroup][]*analyzer.Issue)
+	binaries := make(map[string]bool)
+	callGraphs := make([]string, 0)
 	for _, issue := range issues {
-		groupedIssues[issue.Message] = append(groupedIssues[issue.Message], issue)
+		group := issueGroup{binary: issue.Binary, message: issue.Message}
+		groupedIssues[group] = append(groupedIssues[group], issue)
+		binaries[issue.Binary] = true
+		if issue.CallGraph != "" && !slices.Contains(callGraphs, issue.CallGraph) {
+			callGraphs = append(callGraphs, issue.CallGraph)
+		}
 	}
 	totalIssues := len(groupedIssues)
 
-	// Sort issue messages for consistent output
+	// Sort issue groups for consistent output
 	numOfCriticalIssues := 0
-	var sortedMessages = make([]string, 0, len(groupedIssues))
-	for msg, val := range groupedIssues {
+	var sortedGroups = make([]issueGroup, 0, len(groupedIssues))
+	for group, val := range groupedIssues {
 		if val[0].Severity == analyzer.IssueSeverityCritical {
 			numOfCriticalIssues++
 		}
-		sortedMessages = append(sortedMessages, msg)
+		sortedGroups = append(sortedGroups, group)
 	}
-	sort.Strings(sortedMessages)
+	sort.Slice(sortedGroups, func(i, j int) bool {
+		if sortedGroups[i].binary != sortedGroups[j].binary {
+			return sortedGroups[i].binary < sortedGroups[j].binary
+		}
+		return sortedGroups[i].message < sortedGroups[j].message
+	})
 
 	// Build report template
 	var report strings.Builder
@@ -60,6 +79,9 @@ func (r *TextRenderer) Render(issues []*analyzer.Issue, output io.Writer) error
 	report.WriteString(fmt.Sprintf("⚙️ GOOS: %s\n", r.profile.GOOS))
 	report.WriteString(fmt.Sprintf("🛠 GOARCH: %s\n", r.profile.GOARCH))
 	report.WriteString(fmt.Sprintf("📅 Timestamp: %s\n", timestamp))
+	if len(callGraphs) > 0 {
+		report.WriteString(fmt.Sprintf("🕸 Call Graph: %s\n", strings.Join(callGraphs, ", ")))
+	}
 	report.WriteString("🔢 Analyzer Version: 1.0.0\n\n")
 	report.WriteString("------------------------------\n")
 	report.WriteString("🚨 Summary of Issues\n")
@@ -73,9 +95,15 @@ func (r *TextRenderer) Render(issues []*analyzer.Issue, output io.Writer) error
 
 	// Issues Section
 	issueCounter := 1
-	for _, msg := range sortedMessages {
-		groupedIssue := groupedIssues[msg]
-		report.WriteString(fmt.Sprintf("%d. [%s] %s\n", issueCounter, groupedIssue[0].Severity, msg))
+	currentBinary := ""
+	for i, group := range sortedGroups {
+		// Key the issues by binary when several programs were analyzed
+		if len(binaries) > 1 && (i == 0 || group.binary != currentBinary) {
+			currentBinary = group.binary
+			report.WriteString(fmt.Sprintf("📦 Binary: %s\n\n", currentBinary))
+		}
+		groupedIssue := groupedIssues[group]
+		report.WriteString(fmt.Sprintf("%d. [%s] %s\n", issueCounter, groupedIssue[0].Severity, group.message))
 		if len(groupedIssue[0].Impact) > 0 {
 			report.WriteString(fmt.Sprintf("   - Impact: %s \n", groupedIssue[0].Impact))
 		}
@@ -103,21 +131,30 @@ func (r *TextRenderer) Render(issues []*analyzer.Issue, output io.Writer) error
 }
 
 func buildCallStack(output io.Writer, source *analyzer.CallStack, str string) string {
+	if source == nil { // Issues about the program as a whole have no call stack
+		return str
+	}
 	var fileInfo string
-	if output == os.Stdout {
+	switch {
+	case source.File == "": // An instruction without a Go source position
+		fileInfo = "unknown source"
+	case output == os.Stdout:
 		fileInfo = fmt.Sprintf(
 			" \033[94m\033]8;;file://%s:%d\033\\%s:%d\033]8;;\033\\\033[0m",
 			source.AbsPath, source.Line, source.File, source.Line,
 		)
-	} else {
+	default:
 		fileInfo = fmt.Sprintf("%s:%d (%s)", source.File, source.Line, source.AbsPath)
 	}
 
-	str = strings.Join(
-		[]string{
-			str,
-			fmt.Sprintf("-> %s : (%s)", fileInfo, source.Function)},
-		"\n       ")
+	frame := fmt.Sprintf("-> %s : (%s)", fileInfo, source.Function)
+	if source.Inlined {
+		frame += " (inlined)"
+	}
+	if source.Disassembly != nil {
+		frame = fmt.Sprintf("%s [%s]", frame, source.Disassembly)
+	}
+	str = strings.Join([]string{str, frame}, "\n       ")
 	if source.CallStack != nil {
 		return buildCallStack(output, source.CallStack, str)
 	}